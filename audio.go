@@ -2,6 +2,7 @@ package main
 
 import (
 	"bytes"
+	"fmt"
 	"io"
 	"math/rand"
 
@@ -10,12 +11,15 @@ import (
 )
 
 var (
-	decodeda = make(map[string]*mp3.Stream)
+	decodeda = make(map[string]io.ReadSeeker)
 	audioctx *audio.Context
 )
 
-func newsoundcnv(rd io.Reader) func(bool) *audio.Player {
-	pl, _ := audio.NewPlayer(audioctx, rd)
+func newsoundcnv(name string) (func(bool) *audio.Player, error) {
+	pl, err := newplayer(name)
+	if err != nil {
+		return nil, err
+	}
 	return func(stfu bool) *audio.Player {
 		if !pl.IsPlaying() {
 			pl.Rewind()
@@ -25,36 +29,56 @@ func newsoundcnv(rd io.Reader) func(bool) *audio.Player {
 			pl.Pause()
 		}
 		return pl
-	}
+	}, nil
 }
 
-func newoneshot(rds ...io.Reader) func() {
-	pls := make([]*audio.Player, len(rds))
+func newoneshot(rng *rand.Rand, names ...string) (func(), error) {
+	pls := make([]*audio.Player, len(names))
 	for i := range pls {
-		pls[i], _ = audio.NewPlayer(audioctx, rds[i])
+		var err error
+		if pls[i], err = newplayer(names[i]); err != nil {
+			return nil, err
+		}
 	}
 	return func() {
-		i := rand.Intn(len(pls))
+		i := rng.Intn(len(pls))
 		if !pls[i].IsPlaying() {
 			pls[i].Rewind()
 		}
 		pls[i].Play()
+	}, nil
+}
+
+func newplayer(name string) (*audio.Player, error) {
+	rd := decodeda[name]
+	if rd == nil {
+		return nil, fmt.Errorf("sound %s is not loaded", name)
+	}
+	pl, err := audio.NewPlayer(audioctx, rd)
+	if err != nil {
+		return nil, fmt.Errorf("sound %s: %w", name, err)
 	}
+	return pl, nil
 }
 
-func audioinit() {
+func audioinit() error {
 	audioctx = audio.NewContext(44100) // get from files
-	dec := func(name string) *mp3.Stream {
-		f, err := audiofs.ReadFile(name)
+	var errs errlist
+	dec := func(name string) io.ReadSeeker {
+		f := assets[name]
+		if f == nil {
+			// Half a second of silence for whatever is missing.
+			return bytes.NewReader(make([]byte, 44100*4/2))
+		}
+		s, err := mp3.DecodeWithSampleRate(44100, bytes.NewReader(f))
 		if err != nil {
-			panic(err)
+			errs.add(fmt.Errorf("%s: %w", name, err))
+			return nil
 		}
-		fs := bytes.NewReader(f)
-		s, _ := mp3.DecodeWithSampleRate(44100, fs)
 		return s
 	}
 
-	decodeda = map[string]*mp3.Stream{
+	decodeda = map[string]io.ReadSeeker{
 		"intro": dec("audio/menu_music.mp3"),
 		"game1": dec("audio/level1_music.mp3"),
 		"expl0": dec("audio/explode0.mp3"),
@@ -65,5 +89,5 @@ func audioinit() {
 		"spawn": dec("audio/spawn_fadeout.mp3"),
 		"buzz":  dec("audio/poweron.mp3"),
 	}
-
+	return errs.err()
 }
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"math"
+	"os"
+
+	"github.com/neputevshina/ldjam49/sim"
+)
+
+// budget is the cheapest way to the target of a level.
+type budget struct {
+	path []sim.Point
+	cost float64 // estimated stamina the trip costs
+	ok   bool    // target is reachable at all
+}
+
+// cheapest searches the tile grid for the path to the target that costs
+// the least stamina: every tick costs sim.Tickcost and more on damaging
+// and slow tiles, and every tick in reach of a device Drain more, until
+// the device burns out. The cost is an estimate, not a bound: jitter and
+// wall hits are not counted, which makes it low, and neither are falloff
+// of the drain and blasts, which makes it high. If draining charges the
+// ball it is counted as free.
+func cheapest(l *sim.Level) budget {
+	var targs, devs []sim.Flammable
+	for _, f := range l.Flams {
+		if f.Typ == sim.Ftarg {
+			targs = append(targs, f)
+		} else {
+			devs = append(devs, f)
+		}
+	}
+	centre := func(p sim.Point) (float64, float64) {
+		return float64(p.X) + 0.5, float64(p.Y) + 0.5
+	}
+	goal := func(p sim.Point) bool {
+		x, y := centre(p)
+		for i := range targs {
+			if l.Rules.Drains(l, &targs[i], x, y) {
+				return true
+			}
+		}
+		return false
+	}
+	rate := func(p sim.Point) float64 {
+		x, y := centre(p)
+		n := 0.0
+		for i := range devs {
+			if l.Rules.Drains(l, &devs[i], x, y) {
+				n++
+			}
+		}
+		if l.Rules.Split {
+			n = math.Min(n, 1)
+		}
+		if l.Rules.Charge {
+			n = 0
+		}
+		return l.Rate(p) + n*l.Rules.Drain
+	}
+
+	from := sim.Point{X: int(l.Plx), Y: int(l.Ply)}
+	path, _, ok := l.Path(from, goal, rate)
+	if !ok {
+		return budget{}
+	}
+
+	// The search doesn't know devices burn out, so count the path again
+	// with every device draining only until it's dead.
+	left := make([]float64, len(devs))
+	for i := range devs {
+		if !l.Rules.Charge {
+			left[i] = devs[i].Dur + 2*l.Rules.Drain
+		}
+	}
+	cost := l.Rules.Drain // the tick on the target
+	for i := 1; i < len(path); i++ {
+		a, b := path[i-1], path[i]
+		ticks := math.Hypot(float64(b.X-a.X), float64(b.Y-a.Y)) / sim.Speed
+		cost += ticks * (l.Rate(a) + l.Rate(b)) / 2
+		x, y := centre(b)
+		for j := range devs {
+			if l.Rules.Drains(l, &devs[j], x, y) {
+				d := math.Min(ticks*l.Rules.Drain, left[j])
+				left[j] -= d
+				cost += d
+			}
+		}
+	}
+	return budget{path: path, cost: cost, ok: true}
+}
+
+// analyze reports for every level whether the target can be reached and
+// about how much of the Stamina the cheapest trip leaves.
+func analyze(args []string) int {
+	fs := flag.NewFlagSet("analyze", flag.ExitOnError)
+	low := fs.Float64("low", 0.15, "flag levels that leave less than this part of Stamina")
+	high := fs.Float64("high", 0.8, "flag levels that leave more than this part of Stamina")
+	fs.Parse(args)
+	path := defproject
+	switch fs.NArg() {
+	case 0:
+	case 1:
+		path = fs.Arg(0)
+	default:
+		usage()
+		return 2
+	}
+	proj, err := openproject(path)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	bad := 0
+	for _, lv := range proj.Levels {
+		l, err := sim.Load(proj, lv)
+		if err != nil {
+			fmt.Println(err)
+			bad++
+			continue
+		}
+		b := cheapest(l)
+		if !b.ok {
+			fmt.Printf("%-10s stamina %6.2f  target unreachable\n", l.Name, l.Stamina)
+			bad++
+			continue
+		}
+		margin := l.Stamina - b.cost
+		note := ""
+		switch {
+		case margin < 0:
+			note = "  can't be cleared"
+		case margin < *low*l.Stamina:
+			note = "  too little margin"
+		case margin > *high*l.Stamina:
+			note = "  too much margin"
+		}
+		if note != "" {
+			bad++
+		}
+		fmt.Printf("%-10s stamina %6.2f  est %6.2f  margin %6.2f (%3.0f%%)  %d tiles%s\n",
+			l.Name, l.Stamina, b.cost, margin, 100*margin/l.Stamina, len(b.path), note)
+	}
+	if bad > 0 {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"math"
+
+	"github.com/neputevshina/ldjam49/sim"
+)
+
+// botticks is how long the bot may play a level before giving up.
+const botticks = 60 * 60 * 5
+
+// leg is a part of the bot's plan: walk the path, then stay until the
+// device dev is dead, if there is one.
+type leg struct {
+	path []sim.Point
+	dev  int
+}
+
+func centre(p sim.Point) (float64, float64) {
+	return float64(p.X) + 0.5, float64(p.Y) + 0.5
+}
+
+func reachof(l *sim.Level, f *sim.Flammable) func(sim.Point) bool {
+	return func(p sim.Point) bool {
+		x, y := centre(p)
+		return l.Rules.Drains(l, f, x, y)
+	}
+}
+
+// plan greedily picks devices to drain on the way to the target. Each
+// next device is the one with the most score per stamina spent, as long
+// as after draining it the target is still reachable with safety
+// stamina to spare.
+func plan(l *sim.Level, safety float64) ([]leg, bool) {
+	targ := -1
+	var devs []int
+	for i, f := range l.Flams {
+		if f.Typ == sim.Ftarg {
+			if targ < 0 {
+				targ = i
+			}
+		} else {
+			devs = append(devs, i)
+		}
+	}
+	if targ < 0 {
+		return nil, false
+	}
+	totarg := func(from sim.Point) ([]sim.Point, float64, bool) {
+		return l.Path(from, reachof(l, &l.Flams[targ]), l.Rate)
+	}
+
+	pos := sim.Point{X: int(l.Plx), Y: int(l.Ply)}
+	sta := l.Stamina
+	done := make(map[int]bool)
+	var legs []leg
+	for {
+		best, bestv := -1, 0.0
+		var bestp []sim.Point
+		var bestc float64
+		for _, d := range devs {
+			if done[d] {
+				continue
+			}
+			f := &l.Flams[d]
+			p, c, ok := l.Path(pos, reachof(l, f), l.Rate)
+			if !ok {
+				continue
+			}
+			ticks := f.Dur/l.Rules.Drain + 2
+			c += ticks * (sim.Tickcost + drainrate(l))
+			_, back, ok := totarg(p[len(p)-1])
+			if !ok || sta-c-back < safety {
+				continue
+			}
+			// With charge a device can pay for itself, it is as good as
+			// it gets then.
+			if v := ticks * float64(f.Score) / math.Max(c, 0.01); v > bestv {
+				best, bestv, bestp, bestc = d, v, p, c
+			}
+		}
+		if best < 0 {
+			break
+		}
+		done[best] = true
+		legs = append(legs, leg{path: bestp, dev: best})
+		pos = bestp[len(bestp)-1]
+		sta = math.Min(sta-bestc, l.Stamina)
+	}
+
+	p, _, ok := totarg(pos)
+	if !ok {
+		return nil, false
+	}
+	return append(legs, leg{path: p, dev: -1}), true
+}
+
+// drainrate is the stamina a tick of draining costs, less than 0 if it
+// charges the ball.
+func drainrate(l *sim.Level) float64 {
+	if l.Rules.Charge {
+		return -l.Rules.Drain * l.Rules.Gain
+	}
+	return l.Rules.Drain
+}
+
+// steer presses the keys that move the player from x, y towards tx, ty.
+func steer(x, y, tx, ty float64) sim.Input {
+	const dead = 0.1
+	dx, dy := tx-x, ty-y
+	return sim.Input{
+		Up:    dy < -dead,
+		Down:  dy > dead,
+		Left:  dx < -dead,
+		Right: dx > dead,
+	}
+}
+
+// autoplay plays the level by the plan and returns the inputs it used.
+// With momentum it steers from where the ball would coast to, so that it
+// brakes in time.
+func autoplay(w *sim.World, legs []leg) []sim.Input {
+	lead := 0.0
+	if w.Phys.Mode == sim.Mmomentum {
+		lead = 1 / (1 - w.Phys.Friction)
+	}
+	to := func(tx, ty float64) sim.Input {
+		return steer(w.Plx+w.Vx*lead, w.Ply+w.Vy*lead, tx, ty)
+	}
+	var ins []sim.Input
+	step := func(in sim.Input) bool {
+		ins = append(ins, in)
+		w.Step(in)
+		return !w.Dead && !w.Clear && len(ins) < botticks
+	}
+	for _, lg := range legs {
+		for _, p := range lg.path {
+			tx, ty := centre(p)
+			for math.Abs(w.Plx-tx) > 0.25 || math.Abs(w.Ply-ty) > 0.25 {
+				if !step(to(tx, ty)) {
+					return ins
+				}
+			}
+		}
+		if lg.dev < 0 {
+			continue
+		}
+		tx, ty := centre(lg.path[len(lg.path)-1])
+		for !w.Flams[lg.dev].Dead {
+			if !step(to(tx, ty)) {
+				return ins
+			}
+		}
+	}
+	// The last waypoint is in reach of the target, so this only happens
+	// if jitter kept the player just out of it.
+	last := legs[len(legs)-1].path
+	tx, ty := centre(last[len(last)-1])
+	for step(to(tx, ty)) {
+	}
+	return ins
+}
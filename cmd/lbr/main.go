@@ -0,0 +1,52 @@
+// Command lbr is a set of tools for levels and replays of the game that
+// run without a display.
+//
+//	lbr verify [-project path.ldtk] [-score N] run.replay
+//	lbr validate [path.ldtk]
+//	lbr analyze [-low part] [-high part] [path.ldtk]
+//	lbr par [-seeds N] [-o file] [-record dir] [path.ldtk]
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/neputevshina/ldjam49/sim"
+)
+
+const defproject = "res/data/2021.ldtk"
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: lbr verify [-project path.ldtk] [-score N] run.replay")
+	fmt.Fprintln(os.Stderr, "       lbr validate [path.ldtk]")
+	fmt.Fprintln(os.Stderr, "       lbr analyze [-low part] [-high part] [path.ldtk]")
+	fmt.Fprintln(os.Stderr, "       lbr par [-seeds N] [-o file] [-record dir] [path.ldtk]")
+}
+
+func openproject(path string) (*sim.Project, error) {
+	proj, err := sim.OpenProject(path)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return proj, nil
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+	switch os.Args[1] {
+	case "verify":
+		os.Exit(verify(os.Args[2:]))
+	case "validate":
+		os.Exit(validate(os.Args[2:]))
+	case "analyze":
+		os.Exit(analyze(os.Args[2:]))
+	case "par":
+		os.Exit(par(os.Args[2:]))
+	default:
+		usage()
+		os.Exit(2)
+	}
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/neputevshina/ldjam49/sim"
+)
+
+// parrun is the best bot run of a level.
+type parrun struct {
+	name  string
+	clear bool
+	score int
+	ticks uint
+	sta   float64
+	seed  int64
+	ins   []sim.Input
+}
+
+// parof plays the level with every seed and a few safety margins and
+// keeps the best clearing run.
+func parof(l *sim.Level, seeds int) parrun {
+	best := parrun{name: l.Name, score: -1}
+	for _, safety := range []float64{1, 2, 4, 8} {
+		legs, ok := plan(l, safety)
+		if !ok {
+			return best
+		}
+		for seed := int64(1); seed <= int64(seeds); seed++ {
+			w := sim.New(l, seed)
+			ins := autoplay(w, legs)
+			if w.Clear && w.Newscore > best.score {
+				best = parrun{l.Name, true, w.Newscore, w.Tick, w.Stamina, seed, ins}
+			}
+		}
+	}
+	return best
+}
+
+// par lets the bot play every level and writes the table of the best
+// scores it got.
+func par(args []string) int {
+	fs := flag.NewFlagSet("par", flag.ExitOnError)
+	seeds := fs.Int("seeds", 8, "seeds to try for each level")
+	out := fs.String("o", "", "write the table to this file instead of stdout")
+	record := fs.String("record", "", "save replays of the best runs into this directory")
+	fs.Parse(args)
+	path := defproject
+	switch fs.NArg() {
+	case 0:
+	case 1:
+		path = fs.Arg(0)
+	default:
+		usage()
+		return 2
+	}
+	proj, err := openproject(path)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	var w io.Writer = os.Stdout
+	if *out != "" {
+		f, err := os.Create(*out)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		defer f.Close()
+		w = f
+	}
+
+	failed := 0
+	fmt.Fprintf(w, "%-10s %6s %6s %8s %s\n", "level", "par", "ticks", "stamina", "seed")
+	for i, lv := range proj.Levels {
+		l, err := sim.Load(proj, lv)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			failed++
+			continue
+		}
+		r := parof(l, *seeds)
+		if !r.clear {
+			fmt.Fprintf(w, "%-10s %6s\n", r.name, "-")
+			failed++
+			continue
+		}
+		fmt.Fprintf(w, "%-10s %6d %6d %8.2f %d\n", r.name, r.score, r.ticks, r.sta, r.seed)
+		if *record != "" {
+			rp := &sim.Replay{Version: sim.Version, Lvl: i, Seed: r.seed, Mode: l.Phys.Mode, Inputs: r.ins}
+			if err := saveparreplay(filepath.Join(*record, r.name+".replay"), rp); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+			}
+		}
+	}
+	if failed > 0 {
+		fmt.Fprintf(os.Stderr, "the bot didn't clear %d levels\n", failed)
+		return 1
+	}
+	return 0
+}
+
+func saveparreplay(path string, r *sim.Replay) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if _, err := r.WriteTo(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/neputevshina/ldjam49/sim"
+)
+
+// validate prints every problem in every level of the project and fails
+// if there are any.
+func validate(args []string) int {
+	path := defproject
+	switch len(args) {
+	case 0:
+	case 1:
+		path = args[0]
+	default:
+		usage()
+		return 2
+	}
+	proj, err := openproject(path)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	if len(proj.Levels) == 0 {
+		fmt.Fprintf(os.Stderr, "%s has no levels\n", path)
+		return 1
+	}
+
+	n := 0
+	for _, lv := range proj.Levels {
+		for _, p := range sim.Validate(proj, lv) {
+			fmt.Println(p)
+			n++
+		}
+	}
+	if n > 0 {
+		fmt.Fprintf(os.Stderr, "%d problems in %s\n", n, path)
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/neputevshina/ldjam49/sim"
+)
+
+// verify plays a replay back and prints how it ends. With -score it
+// fails if the replay doesn't score exactly that.
+func verify(args []string) int {
+	fs := flag.NewFlagSet("verify", flag.ExitOnError)
+	project := fs.String("project", defproject, "LDtk project the replay was played on")
+	score := fs.Int("score", -1, "score the replay claims")
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		usage()
+		return 2
+	}
+
+	f, err := os.Open(fs.Arg(0))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	r, err := sim.ReadReplay(f)
+	f.Close()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", fs.Arg(0), err)
+		return 1
+	}
+	if r.Version != sim.Version {
+		fmt.Fprintf(os.Stderr, "warning: replay is of version %s, this is %s\n", r.Version, sim.Version)
+	}
+
+	proj, err := openproject(*project)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	if r.Lvl >= len(proj.Levels) {
+		fmt.Fprintf(os.Stderr, "replay is of level %d, there are only %d\n", r.Lvl, len(proj.Levels))
+		return 1
+	}
+	l, err := sim.Load(proj, proj.Levels[r.Lvl])
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	w := sim.New(l, r.Seed)
+	w.Phys.Mode = r.Mode
+	for _, in := range r.Inputs {
+		w.Step(in)
+		if w.Dead || w.Clear {
+			break
+		}
+	}
+	outcome := "unfinished"
+	switch {
+	case w.Clear:
+		outcome = "clear"
+	case w.Dead:
+		outcome = "dead"
+	}
+	fmt.Printf("level   %s\n", l.Name)
+	fmt.Printf("seed    %d\n", r.Seed)
+	fmt.Printf("mode    %v\n", r.Mode)
+	fmt.Printf("outcome %s\n", outcome)
+	fmt.Printf("ticks   %d\n", w.Tick)
+	fmt.Printf("stamina %.2f\n", w.Stamina)
+	fmt.Printf("score   %d\n", w.Newscore)
+
+	if *score >= 0 && *score != w.Newscore {
+		fmt.Fprintf(os.Stderr, "claimed score %d, replay scores %d\n", *score, w.Newscore)
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,85 @@
+// Package ctl is the controls of the game: the actions and how a gamepad
+// maps to them and to movement. It doesn't touch ebiten, the game reads
+// the gamepad into a Pad every tick.
+package ctl
+
+import (
+	"math"
+
+	"github.com/neputevshina/ldjam49/sim"
+)
+
+// Actions the player can bind keys to.
+const (
+	Aup = iota
+	Adown
+	Aleft
+	Aright
+	Aconfirm
+	Apause
+	Nactions
+)
+
+// Stick positions shorter than this are noise.
+const deadzone = 0.2
+
+// padbuttons are the raw buttons of actions on a gamepad. The numbers
+// are those of an XInput pad as GLFW sees it: A, Start, then the d-pad.
+var padbuttons = [Nactions][]int{
+	Aup:      {11},
+	Aright:   {12},
+	Adown:    {13},
+	Aleft:    {14},
+	Aconfirm: {0},
+	Apause:   {7},
+}
+
+// Pad is a gamepad during one tick.
+type Pad struct {
+	X, Y float64 // left stick
+	Held []bool  // by raw button
+	Just []bool
+}
+
+func (p Pad) button(bs []bool, act int) bool {
+	for _, b := range padbuttons[act] {
+		if b < len(bs) && bs[b] {
+			return true
+		}
+	}
+	return false
+}
+
+// Justpressed reports whether a button of the action went down this tick.
+func (p Pad) Justpressed(act int) bool {
+	return p.button(p.Just, act)
+}
+
+// stick is the stick out of the dead zone, rescaled so that the edge of
+// the dead zone is 0 and all the way is 1.
+func (p Pad) stick() (x, y float64) {
+	l := math.Sqrt(p.X*p.X + p.Y*p.Y)
+	if l < deadzone {
+		return 0, 0
+	}
+	k := math.Min((l-deadzone)/(1-deadzone), 1) / l
+	return p.X * k, p.Y * k
+}
+
+// Input is the movement from the pad: the stick with its strength, or
+// the d-pad at full speed if the stick is let go.
+func (p Pad) Input() sim.Input {
+	x, y := p.stick()
+	if x == 0 && y == 0 {
+		return sim.Input{
+			Up:    p.button(p.Held, Aup),
+			Left:  p.button(p.Held, Aleft),
+			Down:  p.button(p.Held, Adown),
+			Right: p.button(p.Held, Aright),
+		}
+	}
+	q := func(v float64) int8 {
+		return int8(math.Round(math.Max(-1, math.Min(1, v)) * 127))
+	}
+	return sim.Input{Sx: q(x), Sy: q(y)}
+}
@@ -0,0 +1,89 @@
+package ctl
+
+import (
+	"math"
+	"testing"
+
+	"github.com/neputevshina/ldjam49/sim"
+)
+
+func TestStick(t *testing.T) {
+	for _, c := range []struct {
+		x, y   float64
+		wx, wy float64
+	}{
+		{0, 0, 0, 0},
+		{0.1, -0.15, 0, 0}, // in the dead zone
+		{0.6, 0, 0.5, 0},   // halfway out of it
+		{0, -1, 0, -1},
+		{1, 1, math.Sqrt2 / 2, math.Sqrt2 / 2}, // past the edge
+	} {
+		x, y := Pad{X: c.x, Y: c.y}.stick()
+		if math.Abs(x-c.wx) > 1e-9 || math.Abs(y-c.wy) > 1e-9 {
+			t.Errorf("stick %v, %v is %.3f, %.3f, want %.3f, %.3f", c.x, c.y, x, y, c.wx, c.wy)
+		}
+	}
+}
+
+// fakepad is a pad with the raw buttons held.
+func fakepad(x, y float64, held ...int) Pad {
+	p := Pad{X: x, Y: y, Held: make([]bool, 16), Just: make([]bool, 16)}
+	for _, b := range held {
+		p.Held[b] = true
+		p.Just[b] = true
+	}
+	return p
+}
+
+func TestPadInput(t *testing.T) {
+	for _, c := range []struct {
+		name string
+		pad  Pad
+		want sim.Input
+	}{
+		{"nothing", fakepad(0, 0), sim.Input{}},
+		{"d-pad", fakepad(0, 0, 11, 14), sim.Input{Up: true, Left: true}},
+		{"d-pad in dead zone", fakepad(0.1, 0.1, 13), sim.Input{Down: true}},
+		{"stick", fakepad(1, 0), sim.Input{Sx: 127}},
+		{"part of the stick", fakepad(0, 0.8), sim.Input{Sy: 95}},
+		{"stick over d-pad", fakepad(-1, 0, 12), sim.Input{Sx: -127}},
+	} {
+		if got := c.pad.Input(); got != c.want {
+			t.Errorf("%s: %+v, want %+v", c.name, got, c.want)
+		}
+	}
+}
+
+func TestPadButtons(t *testing.T) {
+	acts := func(p Pad) (on []int) {
+		for a := 0; a < Nactions; a++ {
+			if p.Justpressed(a) {
+				on = append(on, a)
+			}
+		}
+		return on
+	}
+	for _, c := range []struct {
+		name string
+		pad  Pad
+		want []int
+	}{
+		{"A", fakepad(0, 0, 0), []int{Aconfirm}},
+		{"Start", fakepad(0, 0, 7), []int{Apause}},
+		{"d-pad", fakepad(0, 0, 11, 12, 13, 14), []int{Aup, Adown, Aleft, Aright}},
+		{"other", fakepad(0, 0, 1, 2, 3), nil},
+		{"no buttons", Pad{}, nil},
+	} {
+		got := acts(c.pad)
+		if len(got) != len(c.want) {
+			t.Errorf("%s: actions %v, want %v", c.name, got, c.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != c.want[i] {
+				t.Errorf("%s: actions %v, want %v", c.name, got, c.want)
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"errors"
+	"log"
+	"strings"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
+)
+
+// errlist collects errors of the loaders so that all of them are shown
+// at once.
+type errlist []error
+
+func (e *errlist) add(err error) {
+	if err != nil {
+		*e = append(*e, err)
+	}
+}
+
+func (e errlist) err() error {
+	if len(e) == 0 {
+		return nil
+	}
+	s := make([]string, len(e))
+	for i, err := range e {
+		s[i] = err.Error()
+	}
+	return errors.New(strings.Join(s, "\n"))
+}
+
+// fail stops the game on the error screen.
+func fail(g *game, err error) {
+	log.Print(err)
+	g.err = err
+	swstate(g, serror)
+}
+
+// drawerror uses the debug font since the broken asset may be the font.
+func drawerror(g *game, screen *ebiten.Image) {
+	screen.Fill(redcol)
+	const cols = 32
+	lines := []string{"can't load the game:", ""}
+	for _, l := range strings.Split(g.err.Error(), "\n") {
+		for len(l) > cols {
+			lines = append(lines, l[:cols])
+			l = "  " + l[cols:]
+		}
+		lines = append(lines, l)
+	}
+	ebitenutil.DebugPrint(screen, strings.Join(lines, "\n"))
+}
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+	"github.com/neputevshina/ldjam49/ctl"
+)
+
+// pad is the gamepad of this tick, see updpad.
+var pad ctl.Pad
+
+func updpad() {
+	pad = ctl.Pad{}
+	ids := ebiten.GamepadIDs()
+	if len(ids) == 0 {
+		return
+	}
+	id := ids[0]
+	if ebiten.GamepadAxisNum(id) >= 2 {
+		pad.X = ebiten.GamepadAxis(id, 0)
+		pad.Y = ebiten.GamepadAxis(id, 1)
+	}
+	n := ebiten.GamepadButtonNum(id)
+	pad.Held = make([]bool, n)
+	pad.Just = make([]bool, n)
+	for b := 0; b < n; b++ {
+		pad.Held[b] = ebiten.IsGamepadButtonPressed(id, ebiten.GamepadButton(b))
+		pad.Just[b] = inpututil.IsGamepadButtonJustPressed(id, ebiten.GamepadButton(b))
+	}
+}
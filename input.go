@@ -0,0 +1,167 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+	"github.com/neputevshina/ldjam49/ctl"
+	"github.com/neputevshina/ldjam49/sim"
+)
+
+// actnames are the ctl actions in the keys config.
+var actnames = [ctl.Nactions]string{"up", "down", "left", "right", "confirm", "pause"}
+
+var binds = [ctl.Nactions][]ebiten.Key{
+	ctl.Aup:      {ebiten.KeyW, ebiten.KeyArrowUp},
+	ctl.Adown:    {ebiten.KeyS, ebiten.KeyArrowDown},
+	ctl.Aleft:    {ebiten.KeyA, ebiten.KeyArrowLeft},
+	ctl.Aright:   {ebiten.KeyD, ebiten.KeyArrowRight},
+	ctl.Aconfirm: {ebiten.KeyEnter, ebiten.KeySpace},
+	ctl.Apause:   {ebiten.KeyEscape, ebiten.KeyP},
+}
+
+func keyheld(act int) bool {
+	for _, k := range binds[act] {
+		if ebiten.IsKeyPressed(k) {
+			return true
+		}
+	}
+	return false
+}
+
+func justpressed(act int) bool {
+	for _, k := range binds[act] {
+		if inpututil.IsKeyJustPressed(k) {
+			return true
+		}
+	}
+	return pad.Justpressed(act)
+}
+
+// anykey is any move or confirm just pressed, what the menus wait for.
+func anykey() bool {
+	for _, a := range []int{ctl.Aup, ctl.Adown, ctl.Aleft, ctl.Aright, ctl.Aconfirm} {
+		if justpressed(a) {
+			return true
+		}
+	}
+	return false
+}
+
+// readinput is the keys, or the gamepad if no key is held.
+func readinput() sim.Input {
+	in := sim.Input{
+		Up:    keyheld(ctl.Aup),
+		Left:  keyheld(ctl.Aleft),
+		Down:  keyheld(ctl.Adown),
+		Right: keyheld(ctl.Aright),
+	}
+	if in == (sim.Input{}) {
+		return pad.Input()
+	}
+	return in
+}
+
+func parsekey(name string) (ebiten.Key, bool) {
+	for k := ebiten.Key(0); k <= ebiten.KeyMax; k++ {
+		if strings.EqualFold(k.String(), name) {
+			return k, true
+		}
+	}
+	return 0, false
+}
+
+// keysconf is where the bindings are, or "" if there's no config dir.
+func keysconf() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "lbr", "keys.conf")
+}
+
+// loadbinds reads the bindings from the config file. Every line is an
+// action followed by its keys, with ebiten's key names:
+//
+//	up W ArrowUp
+//
+// Actions the file doesn't mention keep the defaults. If there is no
+// file, one with the defaults is written for the player to edit.
+func loadbinds(path string) {
+	if path == "" {
+		return
+	}
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		if err := savebinds(path); err != nil {
+			log.Print(err)
+		}
+		return
+	}
+	if err != nil {
+		log.Print(err)
+		return
+	}
+	defer f.Close()
+	if err := parsebinds(f); err != nil {
+		log.Printf("%s: %v", path, err)
+	}
+}
+
+func parsebinds(rd io.Reader) error {
+	var errs errlist
+	sc := bufio.NewScanner(rd)
+	for n := 1; sc.Scan(); n++ {
+		fs := strings.Fields(sc.Text())
+		if len(fs) == 0 || strings.HasPrefix(fs[0], "#") {
+			continue
+		}
+		act := -1
+		for i, a := range actnames {
+			if a == fs[0] {
+				act = i
+			}
+		}
+		if act < 0 {
+			errs.add(fmt.Errorf("line %d: unknown action %s", n, fs[0]))
+			continue
+		}
+		var keys []ebiten.Key
+		for _, name := range fs[1:] {
+			k, ok := parsekey(name)
+			if !ok {
+				errs.add(fmt.Errorf("line %d: unknown key %s", n, name))
+				continue
+			}
+			keys = append(keys, k)
+		}
+		if len(keys) > 0 {
+			binds[act] = keys
+		}
+	}
+	errs.add(sc.Err())
+	return errs.err()
+}
+
+func savebinds(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	var b strings.Builder
+	b.WriteString("# action and its keys, see ebiten.Key for the names\n")
+	for i, keys := range binds {
+		b.WriteString(actnames[i])
+		for _, k := range keys {
+			b.WriteString(" " + k.String())
+		}
+		b.WriteString("\n")
+	}
+	return os.WriteFile(path, []byte(b.String()), 0644)
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"fmt"
+	"image/color"
+	"math"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
+	"github.com/neputevshina/ldjam49/ctl"
+	"github.com/solarlune/ldtkgo"
+)
+
+const (
+	lsrows = 10 // levels shown at once
+	thumbw = 88
+	thumbh = 72
+)
+
+// thumbs are the drawn thumbnails, made when first shown. A reloaded
+// project has new levels, so they are drawn again.
+var thumbs = make(map[*ldtkgo.Level]*ebiten.Image)
+
+// locked is whether a level can't be picked yet: only the one after the
+// last cleared can, and all of them in debug mode.
+func locked(g *game, lv int) bool {
+	return !g.dbg && lv > g.save.Cleared+1
+}
+
+func updlevels(g *game) {
+	if justpressed(ctl.Apause) {
+		swstate(g, stitle)
+		return
+	}
+	i := pick(&g.cur, len(g.ldtk.Levels))
+	if i < 0 || locked(g, i) {
+		return
+	}
+	startlevel(g, i)
+}
+
+// thumb draws the tiles of the level small enough to fit the thumbnail.
+func thumb(lv *ldtkgo.Level) *ebiten.Image {
+	if t := thumbs[lv]; t != nil {
+		return t
+	}
+	full := ebiten.NewImage(lv.Width, lv.Height)
+	for _, id := range []string{"Flooring", "AutoWalls", "EntityTiles"} {
+		l := lv.LayerByIdentifier(id)
+		if l == nil {
+			continue
+		}
+		for _, t := range l.AllTiles() {
+			op := &ebiten.DrawImageOptions{}
+			op.GeoM.Translate(float64(t.Position[0]), float64(t.Position[1]))
+			full.DrawImage(spritesheet[t.ID], op)
+		}
+	}
+	s := float64(thumbw) / float64(lv.Width)
+	if k := float64(thumbh) / float64(lv.Height); k < s {
+		s = k
+	}
+	t := ebiten.NewImage(thumbw, thumbh)
+	op := &ebiten.DrawImageOptions{}
+	op.GeoM.Scale(s, s)
+	op.GeoM.Translate((thumbw-float64(lv.Width)*s)/2, (thumbh-float64(lv.Height)*s)/2)
+	op.Filter = ebiten.FilterLinear
+	t.DrawImage(full, op)
+	full.Dispose()
+	thumbs[lv] = t
+	return t
+}
+
+func drawlevels(g *game, screen *ebiten.Image) {
+	screen.Fill(color.Black)
+	w := screen.Bounds().Dx()
+	h := screen.Bounds().Dy()
+	skip := int(math.Ceil(basefontlin * 1.2))
+
+	first := g.cur - lsrows/2
+	if first > len(g.ldtk.Levels)-lsrows {
+		first = len(g.ldtk.Levels) - lsrows
+	}
+	if first < 0 {
+		first = 0
+	}
+	for i := first; i < len(g.ldtk.Levels) && i < first+lsrows; i++ {
+		c := color.Color(color.White)
+		switch {
+		case i == g.cur:
+			c = orangcol
+		case locked(g, i):
+			c = color.Gray{0x60}
+		}
+		printleft(screen, []string{g.ldtk.Levels[i].Identifier}, 4, 20+(i-first)*skip, c)
+	}
+
+	lv := g.ldtk.Levels[g.cur]
+	x := float64(w - thumbw - 4)
+	ebitenutil.DrawRect(screen, x-1, 13, thumbw+2, thumbh+2, blucol)
+	op := &ebiten.DrawImageOptions{}
+	op.GeoM.Translate(x, 14)
+	if locked(g, g.cur) {
+		op.ColorM.ChangeHSV(0, 0, 0.3)
+	}
+	screen.DrawImage(thumb(lv), op)
+
+	cx := int(x) + thumbw/2
+	info := "not cleared"
+	if locked(g, g.cur) {
+		info = "locked"
+	} else if best, ok := g.save.Best[lv.Identifier]; ok {
+		info = fmt.Sprint("best ", best)
+	}
+	printlable(screen, []string{info}, cx, 14+thumbh+skip+2, orangcol)
+	if (g.tick/30)%2 == 0 {
+		printlable(screen, []string{`escape to go back`}, w/2, 13*h/14, blucol)
+	}
+}
@@ -3,6 +3,7 @@ package main
 import (
 	"bytes"
 	_ "embed"
+	"flag"
 	"fmt"
 	"image"
 	"image/color"
@@ -10,29 +11,33 @@ import (
 	"log"
 	"math"
 	"math/rand"
+	"time"
 
 	"github.com/hajimehoshi/ebiten/v2"
 	"github.com/hajimehoshi/ebiten/v2/audio"
 	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
 	"github.com/hajimehoshi/ebiten/v2/inpututil"
 	"github.com/hajimehoshi/ebiten/v2/text"
+	"github.com/neputevshina/ldjam49/ctl"
+	"github.com/neputevshina/ldjam49/res"
+	"github.com/neputevshina/ldjam49/sim"
 	"github.com/solarlune/ldtkgo"
 	"golang.org/x/image/font"
 	"golang.org/x/image/font/opentype"
 )
 
 const (
-	tilesize    = 16
+	tilesize    = sim.Tilesize
 	plsize      = tilesize
 	introticks  = 90
 	explticks   = 3
 	deathticks  = 12
+	sparkticks  = 8
 	basefontsiz = 16
 	basefontlin = 8
 	shkamnt     = 30
 	shkpik      = 60
 	scorespeed  = 0.5
-	tileprice   = 0.25
 	flickerpat  = `` +
 		`001001011111010000000000010000111111111111111111100000000000` +
 		`000001111111111111000000000000100000000000000000101001010100` +
@@ -58,12 +63,6 @@ const (
 	ddown
 )
 
-const (
-	ftarg = iota
-	ftoas
-	freg
-)
-
 const (
 	sinit = iota
 	sintro
@@ -73,27 +72,15 @@ const (
 	sclear
 	sdead
 	sendgame
+	serror
+	spause
+	slevels
 )
 
-type flammable struct {
-	typ uint
-	w   uint
-	h   uint
-	img *ebiten.Image
-	rot int
-
-	dur   float64
-	x     float64
-	y     float64
-	dead  bool
-	deadt int
-}
-
 var (
 	plsprites   = make([]*ebiten.Image, 0)
 	explspts    = make([]*ebiten.Image, 0)
 	spritesheet = make(map[int]*ebiten.Image)
-	collider    = make(map[int]struct{})
 	dbgstr      string
 	intropic    *ebiten.Image
 	logopic     *ebiten.Image
@@ -102,34 +89,43 @@ var (
 	playexpl    func()
 	playdeaf    func()
 	playspawn   func()
+	playspark   func()
 	introbgm    func(bool) *audio.Player
 	normalbgm   func(bool) *audio.Player
 	outrobgm    func(bool) *audio.Player
 	flamimgs    = make(map[string]*ebiten.Image)
-	dead11      *ebiten.Image
-	dead22      *ebiten.Image
-	dead21      *ebiten.Image
+	deadimgs    = make(map[string]*ebiten.Image)
 )
 
 type game struct {
-	ldtk  *ldtkgo.Project
+	ldtk  *sim.Project
 	l2    *ldtkgo.Layer
 	walls *ldtkgo.Layer
 	floor *ldtkgo.Layer
 
-	flams []flammable
+	w *sim.World
 
 	score     int
-	newscore  int
-	plx       float64
-	ply       float64
-	lvlw      float64
-	lvlh      float64
-	stamina   float64
-	origsta   float64
 	shkticker uint
-	dead      bool
+	spark     uint
 	lvl       int
+	start     int
+	seed      int64
+	rng       *rand.Rand // picks sounds, only Update draws from it
+	shk       *rand.Rand // screen shake, drawn from as often as Draw runs
+	dbg       bool
+	mode      sim.Mode
+	forcemode bool // mode is used instead of the level's
+
+	err    error
+	replay *sim.Replay
+	rec    []sim.Input
+	recdir string
+	reload *reloader
+	save   *save
+
+	cur  int  // cursor in a menu
+	opts bool // options instead of the pause menu
 
 	state int
 	tick  uint
@@ -141,97 +137,31 @@ type game struct {
 func swstate(g *game, state int) {
 	g.state = state
 	g.tick = 0
+	g.cur = 0
 }
 
-func parseflams(ent []*ldtkgo.Entity) []flammable {
-	fls := make([]flammable, 0, 20)
-	for _, e := range ent {
-		fl := flammable{
-			w:    uint(e.Width / tilesize),
-			h:    uint(e.Height / tilesize),
-			x:    float64(e.Position[0]) / tilesize,
-			y:    float64(e.Position[1]) / tilesize,
-			dead: false,
-		}
-		fl.dur = float64(fl.w*fl.h) * tileprice
-		switch e.Identifier {
-		case "Tv":
-			fl.rot = e.PropertyByIdentifier("Rot").AsInt()
-			fallthrough
-		case "Microwave":
-			fallthrough
-		case "Toaster":
-			fl.typ = freg
-			pf := e.PropertyByIdentifier("Type").Value
-			s := ""
-			if pf == nil {
-				s = e.Identifier
-			} else {
-				s = pf.(string)
-			}
-			fl.img = flamimgs[s]
-		case "Target":
-			fl.typ = ftarg
-			fl.dur = 0
-			fl.rot = e.PropertyByIdentifier("Rot").AsInt()
-			fl.img = flamimgs["Target"]
-		}
-		if e.Identifier != "Player" {
-			fls = append(fls, fl)
-		}
-	}
-	return fls
-}
-
-func suck(g *game) {
-	// dbgstr = ""
-	for i, e := range g.flams {
-		if e.dead {
-			g.flams[i].deadt++
-			continue
-		}
-		r := math.Sqrt(float64(e.w*e.h)/math.Pi) * 1.5
-		x := e.x + float64(e.w)/2
-		y := e.y + float64(e.h)/2
-		plx, ply := x-g.plx, y-g.ply
-		dis := math.Sqrt(plx*plx + ply*ply)
-		if dis <= r {
-			// dbgstr = fmt.Sprint(g.score, e.typ, e.dur, e.dead)
-			g.stamina -= 0.05
-			g.flams[i].dur -= 0.05
-			g.newscore += 5
-			if e.dur <= 0 {
-				g.flams[i].dead = true
-				g.shkticker = 60
-				playexpl()
-			}
-			if e.typ == ftarg {
-				g.newscore += int(g.stamina * 10)
-				swstate(g, sclear)
-			}
-		}
-	}
-}
-
+// drawsuck draws the explosions of destroyed devices. A device set off
+// by a blast dies Blastdelay ticks after the one that set it off, so the
+// explosions of a chain follow one another.
 func drawsuck(g *game, img *ebiten.Image) {
 	w := float64(img.Bounds().Dx())
 	h := float64(img.Bounds().Dy())
-	for _, e := range g.flams {
-		if e.dead && e.deadt < 48 {
+	for _, e := range g.w.Flams {
+		if e.Dead && e.Deadt < 48 && e.Typ != sim.Fnone {
 			op := ebiten.DrawImageOptions{}
-			if e.typ == freg {
+			if e.Typ == sim.Freg {
 				op.GeoM.Translate(
-					(float64(e.x)-g.plx)*tilesize+(w)/2+tilesize/2,
-					(float64(e.y)-g.ply)*tilesize+(h-2*plsize)/2+tilesize*2,
+					(float64(e.X)-g.w.Plx)*tilesize+(w)/2+tilesize/2,
+					(float64(e.Y)-g.w.Ply)*tilesize+(h-2*plsize)/2+tilesize*2,
 				)
 			} else {
 				op.GeoM.Translate(
-					(float64(e.x)-g.plx)*tilesize+(w)/2,
-					(float64(e.y)-g.ply)*tilesize+(h-2*plsize)/2+tilesize,
+					(float64(e.X)-g.w.Plx)*tilesize+(w)/2,
+					(float64(e.Y)-g.w.Ply)*tilesize+(h-2*plsize)/2+tilesize,
 				)
 			}
 			op.CompositeMode = ebiten.CompositeModeLighter
-			img.DrawImage(explspts[e.deadt/4], &op)
+			img.DrawImage(explspts[e.Deadt/4], &op)
 		}
 	}
 }
@@ -248,65 +178,41 @@ func drawintro(g *game, img *ebiten.Image) {
 }
 
 func updplay(g *game) {
-	const speed = 0.05
-	const jitter = 0.06
-
-	pplx, pply := g.plx, g.ply
-	g.stamina -= 0.01
-	if g.stamina < 0 {
-		g.dead = true
-		g.state = sdead
-	}
-
-	suck(g)
-
-	switch {
-	case ebiten.IsKeyPressed(ebiten.KeyW) && ebiten.IsKeyPressed(ebiten.KeyA):
-		g.ply -= speed / math.Sqrt2
-		g.plx -= speed / math.Sqrt2
-	case ebiten.IsKeyPressed(ebiten.KeyA) && ebiten.IsKeyPressed(ebiten.KeyS):
-		g.plx -= speed / math.Sqrt2
-		g.ply += speed / math.Sqrt2
-	case ebiten.IsKeyPressed(ebiten.KeyS) && ebiten.IsKeyPressed(ebiten.KeyD):
-		g.ply += speed / math.Sqrt2
-		g.plx += speed / math.Sqrt2
-	case ebiten.IsKeyPressed(ebiten.KeyD) && ebiten.IsKeyPressed(ebiten.KeyW):
-		g.plx += speed / math.Sqrt2
-		g.ply -= speed / math.Sqrt2
-	case ebiten.IsKeyPressed(ebiten.KeyW):
-		g.ply -= speed
-	case ebiten.IsKeyPressed(ebiten.KeyA):
-		g.plx -= speed
-	case ebiten.IsKeyPressed(ebiten.KeyS):
-		g.ply += speed
-	case ebiten.IsKeyPressed(ebiten.KeyD):
-		g.plx += speed
-	}
-
-	g.plx += (rand.Float64() - 0.5) * 2 * jitter
-	g.ply += (rand.Float64() - 0.5) * 2 * jitter
-
-	plux := int(math.Trunc(g.plx))
-	pluy := int(math.Trunc(g.ply))
-
-	tl := g.walls.AutoTileAt(plux, pluy)
-	f := func() bool {
-		_, k := collider[tl.ID]
-		return k
+	var in sim.Input
+	if g.replay != nil {
+		in = g.replay.Input(g.w.Tick)
+	} else {
+		in = readinput()
+	}
+	g.rec = append(g.rec, in)
+	for _, ev := range g.w.Step(in) {
+		switch ev {
+		case sim.Edead:
+			g.state = sdead
+		case sim.Eexpl:
+			g.shkticker = 60
+			playexpl()
+		case sim.Ewall:
+			if g.shkticker < 20 {
+				g.shkticker = 20
+			}
+			g.spark = sparkticks
+			playspark()
+		case sim.Eover:
+			playspawn()
+		case sim.Eclear:
+			if g.replay == nil {
+				cleared(g)
+			}
+			swstate(g, sclear)
+		}
 	}
-	if tl != nil && f() || !inrang(g) {
-		g.stamina -= 0.1
-		g.ply = pply
-		g.plx = pplx
-
+	if g.w.Dead || g.w.Clear {
+		saverec(g)
+		g.replay = nil
 	}
 }
 
-func inrang(g *game) bool {
-	return g.plx > 0 && g.plx < g.lvlw/tilesize &&
-		g.ply > 0 && g.ply < g.lvlh/tilesize
-}
-
 func drawpl(g *game, screen *ebiten.Image) {
 	W := float64(screen.Bounds().Dx())
 	H := float64(screen.Bounds().Dx())
@@ -318,6 +224,23 @@ func drawpl(g *game, screen *ebiten.Image) {
 	screen.DrawImage(plsprites[(g.tick/3)%6], op)
 }
 
+// drawspark flashes a small explosion over the ball when it bounces.
+func drawspark(g *game, screen *ebiten.Image) {
+	if g.spark == 0 {
+		return
+	}
+	W := float64(screen.Bounds().Dx())
+	H := float64(screen.Bounds().Dx())
+	op := &ebiten.DrawImageOptions{}
+	op.GeoM.Scale(0.5, 0.5)
+	op.GeoM.Translate(
+		(W-plsize)/2,
+		(H-3*plsize)/2,
+	)
+	op.CompositeMode = ebiten.CompositeModeLighter
+	screen.DrawImage(explspts[(sparkticks-g.spark)/2], op)
+}
+
 func drawsprites(g *game, screen *ebiten.Image) {
 	W := float64(screen.Bounds().Dx())
 	H := float64(screen.Bounds().Dx())
@@ -328,8 +251,8 @@ func drawsprites(g *game, screen *ebiten.Image) {
 			i := t.Position[0] / tilesize
 			j := t.Position[1] / tilesize
 			op.GeoM.Translate(
-				(float64(i)-g.plx)*tilesize+(W)/2,
-				(float64(j)-g.ply)*tilesize+(H-2*plsize)/2,
+				(float64(i)-g.w.Plx)*tilesize+(W)/2,
+				(float64(j)-g.w.Ply)*tilesize+(H-2*plsize)/2,
 			)
 			screen.DrawImage(spritesheet[t.ID], op)
 		}
@@ -339,9 +262,17 @@ func drawsprites(g *game, screen *ebiten.Image) {
 	spr(g.l2.AllTiles())
 }
 
-func drawstaminabar(scr *ebiten.Image, sta float64, maxsta float64) {
-	w := float64(scr.Bounds().Dx())
-	ebitenutil.DrawRect(scr, 0, 0, w*sta/maxsta, 4, color.RGBA{0xac, 0x1f, 0x9f, 0xff})
+// drawstaminabar blinks full while the ball is overcharged, faster as
+// the overcharge runs out.
+func drawstaminabar(scr *ebiten.Image, w *sim.World) {
+	sw := float64(scr.Bounds().Dx())
+	if w.Over > 0 {
+		if w.Over > 60 || (w.Over/4)%2 == 0 {
+			ebitenutil.DrawRect(scr, 0, 0, sw, 4, orangcol)
+		}
+		return
+	}
+	ebitenutil.DrawRect(scr, 0, 0, sw*w.Stamina/w.Origsta, 4, color.RGBA{0xac, 0x1f, 0x9f, 0xff})
 }
 
 func printlable(screen *ebiten.Image, lable []string, x, y int, c color.Color) {
@@ -384,12 +315,14 @@ func drawmenu(g *game, screen *ebiten.Image) {
 		`and lulz in three days`,
 		`of october 2021`,
 	}
-	blink := []string{`press any of wasd`}
+	blink := []string{`press a move key`}
 	blink2 := []string{`EPILEPSY WARNING`}
 	scx := screen.Bounds().Dx() / 2
 	sh := screen.Bounds().Dy()
 	printlable(screen, lable, scx, 6*sh/7, color.White)
-	if (g.tick/30)%2 == 0 {
+	if items := titleitems(g); len(items) > 0 {
+		drawitems(screen, items, g.cur, scx, 9*sh/14-20)
+	} else if (g.tick/30)%2 == 0 {
 		printlable(screen, blink, scx, 9*sh/14-8, orangcol)
 	}
 	if (g.tick/31)%2 == 0 {
@@ -404,7 +337,7 @@ func drawmenu2(g *game, screen *ebiten.Image) {
 		`your charge is going down so better hurry.`,
 		`don't hit the walls!`,
 	}
-	blink := []string{`press any of wasd`, `to continue`}
+	blink := []string{`press a move key`, `to continue`}
 	scx := screen.Bounds().Dx() / 2
 	sh := screen.Bounds().Dy()
 	printlable(screen, lable, scx, 3*sh/7, color.White)
@@ -443,7 +376,7 @@ func drawoutro(g *game, screen *ebiten.Image) {
 	op := ebiten.DrawImageOptions{}
 	dx := float64(intropic.Bounds().Dx()) / 2
 	dy := float64(intropic.Bounds().Dy()) / 2
-	printlable(intropic, []string{fmt.Sprint("your score is ", g.newscore)},
+	printlable(intropic, []string{fmt.Sprint("your score is ", g.w.Newscore)},
 		100, int(dy/2), orangcol)
 	op.GeoM.Translate(-dx, -dy)
 	s := (math.Sin(float64(g.tick)/32) + 2) / 2
@@ -465,59 +398,131 @@ func drawoutro(g *game, screen *ebiten.Image) {
 func drawflams(g *game, img *ebiten.Image) {
 	W := float64(img.Bounds().Dx())
 	H := float64(img.Bounds().Dx())
-	for _, e := range g.flams {
+	for _, e := range g.w.Flams {
 		op := ebiten.DrawImageOptions{}
-		op.GeoM.Translate(-float64(e.w*tilesize)/2, -float64(e.h*tilesize)/2)
-		op.GeoM.Rotate(float64(e.rot) * math.Pi / 2)
-		op.GeoM.Translate(float64(e.w*tilesize)/2, float64(e.h*tilesize)/2)
+		op.GeoM.Translate(-float64(e.W*tilesize)/2, -float64(e.H*tilesize)/2)
+		op.GeoM.Rotate(float64(e.Rot) * math.Pi / 2)
+		op.GeoM.Translate(float64(e.W*tilesize)/2, float64(e.H*tilesize)/2)
 		op.GeoM.Translate(
-			(e.x-g.plx)*tilesize+(W)/2,
-			(e.y-g.ply)*tilesize+(H-2*plsize)/2,
+			(e.X-g.w.Plx)*tilesize+(W)/2,
+			(e.Y-g.w.Ply)*tilesize+(H-2*plsize)/2,
 		)
-		if !e.dead {
-			img.DrawImage(e.img, &op)
-		} else {
-			if e.h == 1 && e.w == 1 {
-				img.DrawImage(dead11, &op)
-			} else if e.h == 1 && e.w == 2 {
-				img.DrawImage(dead21, &op)
-			} else if e.h == 2 && e.w == 2 {
-				img.DrawImage(dead22, &op)
+		if !e.Dead {
+			im := flamimgs[e.Sprite]
+			if im == nil {
+				im = flamimgs[""]
+			}
+			img.DrawImage(im, &op)
+			continue
+		}
+		ds := e.Deadsprite
+		if ds == "" {
+			switch {
+			case e.H == 1 && e.W == 1:
+				ds = "Small"
+			case e.H == 1 && e.W == 2:
+				ds = "Wide"
+			case e.H == 2 && e.W == 2:
+				ds = "Big"
 			}
 		}
+		if im := deadimgs[ds]; im != nil {
+			img.DrawImage(im, &op)
+		}
 	}
 }
 
-func anykey() bool {
-	return inpututil.IsKeyJustPressed(ebiten.KeyW) ||
-		inpututil.IsKeyJustPressed(ebiten.KeyA) ||
-		inpututil.IsKeyJustPressed(ebiten.KeyS) ||
-		inpututil.IsKeyJustPressed(ebiten.KeyD)
+// titleitems are the choices on the title screen, none if there is
+// nothing to choose yet and any key starts the game.
+func titleitems(g *game) []string {
+	if g.replay != nil {
+		return nil
+	}
+	if g.save.Cleared >= 0 {
+		return []string{"continue", "levels", "new game"}
+	}
+	if g.dbg {
+		return []string{"new game", "levels"}
+	}
+	return nil
 }
 
 func updmenu(g *game) {
-	if anykey() {
+	items := titleitems(g)
+	if len(items) == 0 {
+		if anykey() {
+			swstate(g, stitle2)
+		}
+		return
+	}
+	i := pick(&g.cur, len(items))
+	if i < 0 {
+		return
+	}
+	switch items[i] {
+	case "continue":
+		startlevel(g, g.save.resume(len(g.ldtk.Levels)))
+	case "levels":
+		swstate(g, slevels)
+	case "new game":
 		swstate(g, stitle2)
 	}
 }
 
+// startlevel starts a level right away with no score.
+func startlevel(g *game, lv int) {
+	g.lvl = lv
+	if err := loadlevel(g, g.lvl); err != nil {
+		fail(g, err)
+		return
+	}
+	g.w.Newscore = 0
+	g.score = 0
+	swstate(g, splay)
+}
+
 func updmenu2(g *game) {
 	if anykey() {
 		swstate(g, splay)
 		g.lvl = 0
-		loadlevel(g, g.lvl)
+		if err := loadlevel(g, g.lvl); err != nil {
+			fail(g, err)
+		}
 	}
 }
 
 func (g *game) Update() error {
 	defer func() { g.tick++ }()
+	updpad()
+	if inpututil.IsKeyJustPressed(ebiten.KeyF3) {
+		g.dbg = !g.dbg
+	}
+	updreload(g)
 	if g.shkticker != 0 {
 		g.shkticker--
 	}
+	if g.spark != 0 {
+		g.spark--
+	}
 	switch g.state {
 	case sinit:
-		gameinit(g)
-		loadlevel(g, 0)
+		if err := gameinit(g); err != nil {
+			fail(g, err)
+			break
+		}
+		if g.start >= 0 {
+			g.lvl = g.start
+			if err := loadlevel(g, g.lvl); err != nil {
+				fail(g, err)
+				break
+			}
+			swstate(g, splay)
+			break
+		}
+		if err := loadlevel(g, 0); err != nil {
+			fail(g, err)
+			break
+		}
 		swstate(g, sintro)
 	case sintro:
 		if g.tick > introticks {
@@ -532,12 +537,21 @@ func (g *game) Update() error {
 		g.score = 0
 	case splay:
 		if g.tick == 1 {
-			g.score = g.newscore
+			g.score = g.w.Newscore
 			playspawn()
 			swbgm(g, normalbgm)
 		}
 		g.bgm(false)
+		if g.tick > 1 && (justpressed(ctl.Apause) || !ebiten.IsFocused()) {
+			pause(g)
+			break
+		}
 		updplay(g)
+	case spause:
+		updpause(g)
+	case slevels:
+		updlevels(g)
+		g.bgm(false)
 	case sclear:
 		if g.tick == 1 {
 			playdeaf()
@@ -545,7 +559,10 @@ func (g *game) Update() error {
 		if g.tick > 60 && anykey() {
 			g.lvl++
 			if g.lvl < len(g.ldtk.Levels) {
-				loadlevel(g, g.lvl)
+				if err := loadlevel(g, g.lvl); err != nil {
+					fail(g, err)
+					break
+				}
 				g.bgm = nil
 				swstate(g, splay)
 			} else {
@@ -555,24 +572,39 @@ func (g *game) Update() error {
 	case sdead:
 		g.bgm(true)
 		if g.tick > 120 && anykey() {
-			loadlevel(g, g.lvl)
-			g.newscore = 0
+			if err := loadlevel(g, g.lvl); err != nil {
+				fail(g, err)
+				break
+			}
+			g.w.Newscore = 0
 			g.score = 0
 			swstate(g, stitle)
 		}
 	case sendgame:
 		if g.tick == 1 {
-			g.score = g.newscore
+			g.score = g.w.Newscore
 			swbgm(g, outrobgm)
 		}
 		g.bgm(false)
+		if g.tick > 120 && (justpressed(ctl.Aconfirm) || justpressed(ctl.Apause)) {
+			g.w.Newscore = 0
+			g.score = 0
+			swbgm(g, introbgm)
+			swstate(g, stitle)
+		}
 	}
 	return nil
 }
 
 func (g *game) Draw(screen *ebiten.Image) {
-	// dbgstr = fmt.Sprint("w: ", g.lvlw, ", h: ", g.lvlh)
-	defer ebitenutil.DebugPrint(screen, dbgstr)
+	// dbgstr = fmt.Sprint("w: ", g.w.Lvl.W, ", h: ", g.w.Lvl.H)
+	defer func() {
+		if g.dbg {
+			ebitenutil.DebugPrint(screen, fmt.Sprint("seed ", g.seed, "\n", dbgstr))
+		} else {
+			ebitenutil.DebugPrint(screen, dbgstr)
+		}
+	}()
 	var fade float64
 	switch g.state {
 	case sintro:
@@ -603,7 +635,7 @@ func (g *game) Draw(screen *ebiten.Image) {
 		screen.Fill(color.RGBA{0xff, 0, 0, 0xff})
 		printlable(screen, []string{"YOU ARE DEAD"}, cw, ch, color.Black)
 		if float64(g.tick) >= 90 && (g.tick/30)%2 == 0 {
-			blink := []string{`press any of wasd`, `to go to title screen`}
+			blink := []string{`press a move key`, `to go to title screen`}
 			printlable(screen, blink, cw, 13*h/14, color.Black)
 		}
 
@@ -628,26 +660,32 @@ func (g *game) Draw(screen *ebiten.Image) {
 		printlable(screen, []string{"Level complete"}, cw, ch,
 			redcol)
 
-		scorepoint := 90 + float64(g.newscore-g.score)*scorespeed
+		scorepoint := 90 + float64(g.w.Newscore-g.score)*scorespeed
 		if g.tick >= 90 {
 			if float64(g.tick) < scorepoint {
 				cur := float64(g.score) + float64(g.tick-90)/scorespeed
 				tickstr := fmt.Sprintf("%.0f", cur)
 				printlable(screen, []string{tickstr}, cw, ch+16, redcol)
 			} else {
-				printlable(screen, []string{fmt.Sprint(g.newscore)}, cw, ch+16, orangcol)
+				printlable(screen, []string{fmt.Sprint(g.w.Newscore)}, cw, ch+16, orangcol)
 			}
 		}
 		if float64(g.tick) >= scorepoint && (g.tick/30)%2 == 0 {
-			blink := []string{`press any of wasd`, `to continue`}
+			blink := []string{`press a move key`, `to continue`}
 			printlable(screen, blink, cw, 13*h/14, blucol)
 		}
 	case splay:
 		drawplayfield(g, screen)
 		drawsuck(g, screen)
-		drawstaminabar(screen, g.stamina, g.origsta)
+		drawstaminabar(screen, g.w)
+	case spause:
+		drawpause(g, screen)
+	case slevels:
+		drawlevels(g, screen)
 	case sendgame:
 		drawoutro(g, screen)
+	case serror:
+		drawerror(g, screen)
 	}
 }
 
@@ -656,9 +694,10 @@ func drawplayfield(g *game, screen *ebiten.Image) {
 	drawsprites(g, g.view)
 	drawflams(g, g.view)
 	drawpl(g, g.view)
+	drawspark(g, g.view)
 	op := &ebiten.DrawImageOptions{}
 	r := func() float64 {
-		return rand.Float64() * shkamnt * float64(g.shkticker) / shkpik
+		return g.shk.Float64() * shkamnt * float64(g.shkticker) / shkpik
 	}
 	op.GeoM.Translate(r(), r())
 	screen.DrawImage(g.view, op)
@@ -668,22 +707,27 @@ func (g *game) Layout(outsideWidth, outsideHeight int) (screenWidth, screenHeigh
 	return 200, 160
 }
 
-func newslicer(data []byte, s int) func(x, y int) *ebiten.Image {
-	return newslicer2(data, s, s)
+func newslicer(name string, s int) (func(x, y int) *ebiten.Image, error) {
+	return newslicer2(name, s, s)
 }
 
-func newslicer2(data []byte, w, h int) func(x, y int) *ebiten.Image {
-	atlimg, _, _ := image.Decode(bytes.NewReader(data))
-	atlas := ebiten.NewImageFromImage(atlimg)
+func newslicer2(name string, w, h int) (func(x, y int) *ebiten.Image, error) {
+	atlas, err := wholeimg(name)
+	if err != nil {
+		return nil, err
+	}
 	return func(x, y int) *ebiten.Image {
 		x, y = x*w, y*h
 		return ebiten.NewImageFromImage(atlas.SubImage(image.Rect(x, y, x+w, y+h)))
-	}
+	}, nil
 }
 
-func wholeimg(data []byte) *ebiten.Image {
-	iimg, _, _ := image.Decode(bytes.NewReader(data))
-	return ebiten.NewImageFromImage(iimg)
+func wholeimg(name string) (*ebiten.Image, error) {
+	iimg, _, err := image.Decode(bytes.NewReader(assets[name]))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	return ebiten.NewImageFromImage(iimg), nil
 }
 
 func swbgm(g *game, f func(bool) *audio.Player) {
@@ -693,16 +737,74 @@ func swbgm(g *game, f func(bool) *audio.Player) {
 	g.bgm = f
 }
 
-func gameinit(g *game) {
-	s11 := newslicer(atlas2, tilesize)
+// gameinit loads everything and returns all errors it met, not only the
+// first one.
+func gameinit(g *game) error {
+	var errs errlist
+	slicer := func(name string, w, h int) func(x, y int) *ebiten.Image {
+		sl, err := newslicer2(name, w, h)
+		errs.add(err)
+		return sl
+	}
+	img := func(name string) *ebiten.Image {
+		im, err := wholeimg(name)
+		errs.add(err)
+		return im
+	}
+	oneshot := func(names ...string) func() {
+		f, err := newoneshot(g.rng, names...)
+		errs.add(err)
+		return f
+	}
+	soundcnv := func(name string) func(bool) *audio.Player {
+		f, err := newsoundcnv(name)
+		errs.add(err)
+		return f
+	}
+
+	s11 := slicer("data/atlas2.png", tilesize, tilesize)
+	s22 := slicer("data/atlas2.png", tilesize*2, tilesize*2)
+	s21 := slicer("data/atlas2.png", tilesize*2, tilesize)
+	d11 := slicer("data/babah16.png", tilesize, tilesize)
+	d21 := slicer("data/babah1632.png", tilesize*2, tilesize)
+	d22 := slicer("data/babah32.png", tilesize*2, tilesize*2)
+	plat := slicer("data/player.png", plsize, plsize)
+	esl := slicer("data/expl.png", 32, 32)
+	intropic = img("data/intro.png")
+	logopic = img("data/logo1.png")
+
+	tt, err := opentype.Parse(assets["data/PKMN-Mystery-Dungeon.ttf"])
+	if err == nil {
+		const dpi = 72
+		dfont, err = opentype.NewFace(tt, &opentype.FaceOptions{
+			Size:    basefontsiz,
+			DPI:     dpi,
+			Hinting: font.HintingFull,
+		})
+	}
+	if err != nil {
+		errs.add(fmt.Errorf("data/PKMN-Mystery-Dungeon.ttf: %w", err))
+	}
+
+	playexpl = oneshot("expl0", "expl1", "expl2")
+	playdeaf = oneshot("deaf")
+	playspawn = oneshot("spawn")
+	playspark = oneshot("buzz")
+	introbgm = soundcnv("intro")
+	normalbgm = soundcnv("game1")
+	outrobgm = soundcnv("outro")
+	g.bgm = introbgm
+
+	if err := errs.err(); err != nil {
+		return err
+	}
+
 	for j := range [16]int{} {
 		for i := range [16]int{} {
 			spritesheet[i+16*j] = s11(i, j)
 		}
 	}
 
-	s22 := newslicer(atlas2, tilesize*2)
-	s21 := newslicer2(atlas2, tilesize*2, tilesize)
 	flamimgs["Tv"] = s22(0, 1)
 	flamimgs["Wash"] = s22(1, 1)
 	flamimgs["Microwave"] = s21(0, 4)
@@ -710,91 +812,142 @@ func gameinit(g *game) {
 	flamimgs["Target"] = s11(3, 4)
 	flamimgs[""] = s11(2, 4)
 
-	dead11 = newslicer(dead11dat, tilesize)(0, 0)
-	dead21 = newslicer2(dead21dat, tilesize*2, tilesize)(0, 0)
-	dead22 = newslicer(dead22dat, tilesize*2)(0, 0)
+	deadimgs["Small"] = d11(0, 0)
+	deadimgs["Wide"] = d21(0, 0)
+	deadimgs["Big"] = d22(0, 0)
 
-	plat := newslicer(kozin, plsize)
 	for i := range [6]int{} {
 		plsprites = append(plsprites, plat(0, i))
 	}
 
-	intropic = wholeimg(introdat)
-	logopic = wholeimg(logodat)
 	lqwhite = ebiten.NewImage(g.view.Size())
 	lqwhite.Fill(color.White)
 
-	tt, err := opentype.Parse(fontdat)
-	if err != nil {
-		log.Fatal(err)
-	}
-	const dpi = 72
-	dfont, err = opentype.NewFace(tt, &opentype.FaceOptions{
-		Size:    basefontsiz,
-		DPI:     dpi,
-		Hinting: font.HintingFull,
-	})
-	playexpl = newoneshot(decodeda["expl0"], decodeda["expl1"], decodeda["expl2"])
-	playdeaf = newoneshot(decodeda["deaf"])
-	playspawn = newoneshot(decodeda["spawn"])
-
-	introbgm = newsoundcnv(decodeda["intro"])
-	normalbgm = newsoundcnv(decodeda["game1"])
-	outrobgm = newsoundcnv(decodeda["outro"])
-	g.bgm = introbgm
-
-	esl := newslicer(expldat, 32)
 	for j := range [3]int{} {
 		for i := range [4]int{} {
 			explspts = append(explspts, esl(i, j))
 		}
 	}
-
+	return nil
 }
 
-func loadlevel(g *game, lv int) {
-	g.lvlw = float64(g.ldtk.Levels[lv].Width)
-	g.lvlh = float64(g.ldtk.Levels[lv].Height)
+func loadlevel(g *game, lv int) error {
+	l, err := sim.Load(g.ldtk, g.ldtk.Levels[lv])
+	if err != nil {
+		return err
+	}
+	setlevel(g, lv, l)
+	return nil
+}
 
+// setlevel starts a new run of the level l, number lv of g.ldtk.
+func setlevel(g *game, lv int, l *sim.Level) {
 	g.walls = g.ldtk.Levels[lv].LayerByIdentifier("AutoWalls")
 	g.floor = g.ldtk.Levels[lv].LayerByIdentifier("Flooring")
 	g.l2 = g.ldtk.Levels[lv].LayerByIdentifier("EntityTiles")
 
-	ent := g.ldtk.Levels[lv].LayerByIdentifier("Entities")
-	pl := ent.EntityByIdentifier("Player")
-	if pl == nil {
-		panic(fmt.Sprint("no player in level ", lv))
+	score := 0
+	if g.w != nil {
+		score = g.w.Newscore
 	}
-
-	g.stamina = pl.PropertyByIdentifier("Stamina").AsFloat64()
-	g.plx = float64(pl.Position[0] / tilesize)
-	g.ply = float64(pl.Position[1] / tilesize)
-	g.origsta = g.stamina
-	g.flams = parseflams(ent.Entities)
+	g.w = sim.New(l, g.seed)
+	if g.forcemode {
+		g.w.Phys.Mode = g.mode
+	}
+	g.w.Newscore = score
+	g.rec = nil
 }
 
-func pregameinit(g *game) {
-	proj, err := ldtkgo.Read(ldtk)
+// pregameinit reads the project from path or the embedded one if path
+// is empty. level, if not empty, is where the game starts.
+func pregameinit(g *game, path, level string) error {
+	var proj *sim.Project
+	var err error
+	if path == "" {
+		path = "res/data/2021.ldtk"
+		proj, err = sim.ReadProject(res.Ldtk)
+	} else {
+		proj, err = sim.OpenProject(path)
+	}
 	if err != nil {
-		panic(err)
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	if len(proj.Levels) == 0 {
+		return fmt.Errorf("%s has no levels", path)
 	}
 	g.ldtk = proj
-	audioinit()
+
+	if g.replay != nil {
+		g.start = g.replay.Lvl
+		if g.start >= len(proj.Levels) {
+			return fmt.Errorf("replay is of level %d, there are only %d", g.start, len(proj.Levels))
+		}
+	} else if level != "" {
+		if g.start, err = sim.FindLevel(proj, level); err != nil {
+			return err
+		}
+	}
+
+	var errs errlist
+	errs.add(checkassets())
+	errs.add(audioinit())
+	return errs.err()
 }
 
 func main() {
+	seed := flag.Int64("seed", time.Now().UnixNano(), "seed of the run")
+	dbg := flag.Bool("debug", false, "show debug overlay (toggle with F3)")
+	replay := flag.String("replay", "", "play back a replay file instead of the keyboard")
+	record := flag.String("record", "", "save a replay of every attempt into this directory")
+	project := flag.String("project", "", "play this LDtk project instead of the built-in one")
+	level := flag.String("level", "", "start right at this level, by number or identifier")
+	keep := flag.Bool("keep", false, "keep player position and stamina when -project is reloaded")
+	movement := flag.String("movement", "", "instant or momentum movement in all levels instead of what the level says")
+	flag.Parse()
+	loadbinds(keysconf())
+	// Progress is only of the built-in levels and real attempts, other
+	// runs start from nothing and don't write it.
+	sv := newsave()
+	if *project == "" && *replay == "" {
+		sv = loadsave(savefile())
+	}
+
 	ebiten.SetWindowResizable(false)
 	ebiten.SetWindowSize(800, 640)
 	ebiten.SetWindowTitle("Lightning Ball Rampage")
 	gm := &game{}
-	gm.plx = 1
-	gm.ply = 1
-	for i := 1; i <= 255; i++ {
-		collider[i] = struct{}{}
-	}
 	gm.lvl = 0
+	gm.start = -1
+	gm.seed = *seed
+	gm.dbg = *dbg
+	gm.recdir = *record
+	gm.save = sv
+	gm.mode, gm.forcemode = sv.mode()
+	if *movement != "" {
+		m, err := sim.ParseMode(*movement)
+		if err != nil {
+			log.Fatal(err)
+		}
+		gm.mode, gm.forcemode = m, true
+	}
+	if *replay != "" {
+		r, err := loadreplay(*replay)
+		if err != nil {
+			log.Fatal(err)
+		}
+		gm.replay = r
+		gm.seed = r.Seed
+		gm.mode, gm.forcemode = r.Mode, true
+	}
+	gm.rng = rand.New(rand.NewSource(gm.seed))
+	gm.shk = rand.New(rand.NewSource(gm.seed))
 	gm.view = ebiten.NewImage(gm.Layout(800, 640))
-	pregameinit(gm)
+	if err := pregameinit(gm, *project, *level); err != nil {
+		fail(gm, err)
+	}
+	if *project != "" {
+		gm.reload = newreloader(*project, *keep)
+	}
 	if err := ebiten.RunGame(gm); err != nil {
 		log.Fatal(err)
 	}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io/fs"
+	"log"
+
+	"github.com/hajimehoshi/ebiten/v2/audio/mp3"
+	"github.com/neputevshina/ldjam49/res"
+	"golang.org/x/image/font/opentype"
+)
+
+// assets holds the checked contents of the manifest by path. Missing
+// sounds are nil.
+var assets = make(map[string][]byte)
+
+// checkassets reads and decodes everything in res.Manifest once. All
+// problems with required assets are reported together in the error.
+func checkassets() error {
+	var errs errlist
+	listed := make(map[string]bool)
+	for _, a := range res.Manifest {
+		listed[a.Path] = true
+		b, err := res.FS.ReadFile(a.Path)
+		if err == nil {
+			err = trydecode(a, b)
+		}
+		if err == nil {
+			assets[a.Path] = b
+			continue
+		}
+		if !a.Opt {
+			errs.add(err)
+			continue
+		}
+		log.Printf("%v, using a placeholder", err)
+		if a.Kind == res.Kimg {
+			assets[a.Path] = placeholder(a.W, a.H)
+		}
+	}
+
+	fs.WalkDir(res.FS, ".", func(path string, d fs.DirEntry, err error) error {
+		if err == nil && !d.IsDir() && !listed[path] {
+			log.Printf("%s is embedded but not in the manifest", path)
+		}
+		return nil
+	})
+
+	return errs.err()
+}
+
+func trydecode(a res.Asset, b []byte) error {
+	var err error
+	switch a.Kind {
+	case res.Kimg:
+		_, _, err = image.Decode(bytes.NewReader(b))
+	case res.Ksnd:
+		_, err = mp3.DecodeWithSampleRate(44100, bytes.NewReader(b))
+	case res.Kfont:
+		_, err = opentype.Parse(b)
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %w", a.Path, err)
+	}
+	return nil
+}
+
+func placeholder(w, h int) []byte {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			if (x/4+y/4)%2 == 0 {
+				img.Set(x, y, color.RGBA{0xff, 0, 0xff, 0xff})
+			}
+		}
+	}
+	var buf bytes.Buffer
+	png.Encode(&buf, img)
+	return buf.Bytes()
+}
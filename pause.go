@@ -0,0 +1,157 @@
+package main
+
+import (
+	"image/color"
+	"math"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
+	"github.com/neputevshina/ldjam49/ctl"
+	"github.com/neputevshina/ldjam49/sim"
+)
+
+// Volume of the music while paused.
+const duckvol = 0.3
+
+// Items of the pause menu.
+const (
+	presume = iota
+	prestart
+	poptions
+	pquit
+)
+
+var pauseitems = []string{"resume", "restart level", "options", "quit to title"}
+
+// pick moves the cursor over n items with up and down and returns the
+// item confirmed, or -1.
+func pick(cur *int, n int) int {
+	if *cur >= n {
+		*cur = 0
+	}
+	if justpressed(ctl.Aup) {
+		*cur = (*cur + n - 1) % n
+	}
+	if justpressed(ctl.Adown) {
+		*cur = (*cur + 1) % n
+	}
+	if justpressed(ctl.Aconfirm) {
+		return *cur
+	}
+	return -1
+}
+
+func drawitems(screen *ebiten.Image, items []string, cur, x, y int) {
+	for i, it := range items {
+		c := color.Color(color.White)
+		if i == cur {
+			c = orangcol
+			it = "> " + it + " <"
+		}
+		printlable(screen, []string{it}, x, y+i*int(math.Ceil(basefontlin*1.2)), c)
+	}
+}
+
+// pause stops the level in the middle and brings up the pause menu.
+func pause(g *game) {
+	g.state = spause
+	g.cur = presume
+	g.opts = false
+	g.bgm(false).SetVolume(duckvol)
+}
+
+// unpause goes back to the level without restarting its tick, so that
+// the spawn sound is not played again.
+func unpause(g *game) {
+	g.state = splay
+	g.bgm(false).SetVolume(1)
+}
+
+func updpause(g *game) {
+	if g.opts {
+		updoptions(g)
+		return
+	}
+	if justpressed(ctl.Apause) {
+		unpause(g)
+		return
+	}
+	switch pick(&g.cur, len(pauseitems)) {
+	case presume:
+		unpause(g)
+	case prestart:
+		g.bgm(false).SetVolume(1)
+		saverec(g)
+		score := g.score
+		if err := loadlevel(g, g.lvl); err != nil {
+			fail(g, err)
+			return
+		}
+		g.w.Newscore = score
+		swstate(g, splay)
+	case poptions:
+		g.opts = true
+		g.cur = 0
+	case pquit:
+		g.bgm(false).SetVolume(1)
+		saverec(g)
+		g.replay = nil
+		g.w.Newscore = 0
+		g.score = 0
+		swbgm(g, introbgm)
+		swstate(g, stitle)
+	}
+}
+
+// optitems are the lines of the options menu as they are now.
+func optitems(g *game) []string {
+	mv := "as the level says"
+	if g.forcemode {
+		mv = g.mode.String()
+	}
+	return []string{"movement: " + mv, "back"}
+}
+
+// updoptions cycles the movement between the level's, instant and
+// momentum. It is used from the next start of a level. A replay has
+// its own movement, it can't be changed.
+func updoptions(g *game) {
+	if justpressed(ctl.Apause) {
+		g.opts = false
+		g.cur = poptions
+		return
+	}
+	switch pick(&g.cur, len(optitems(g))) {
+	case 0:
+		if g.replay != nil {
+			break
+		}
+		switch {
+		case !g.forcemode:
+			g.mode, g.forcemode = sim.Minstant, true
+		case g.mode == sim.Minstant:
+			g.mode = sim.Mmomentum
+		default:
+			g.forcemode = false
+		}
+		g.save.setmode(g.mode, g.forcemode)
+	case 1:
+		g.opts = false
+		g.cur = poptions
+	}
+}
+
+func drawpause(g *game, screen *ebiten.Image) {
+	drawplayfield(g, screen)
+	drawstaminabar(screen, g.w)
+	w := screen.Bounds().Dx()
+	h := screen.Bounds().Dy()
+	ebitenutil.DrawRect(screen, 0, 0, float64(w), float64(h), color.RGBA{0, 0, 0, 0xb0})
+	if g.opts {
+		printlable(screen, []string{"options"}, w/2, h/4, blucol)
+		drawitems(screen, optitems(g), g.cur, w/2, h/2-8)
+		return
+	}
+	printlable(screen, []string{"paused"}, w/2, h/4, blucol)
+	drawitems(screen, pauseitems, g.cur, w/2, h/2-16)
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"log"
+	"os"
+	"time"
+
+	"github.com/neputevshina/ldjam49/sim"
+)
+
+// How often the project file is checked for changes.
+const reloadticks = 30
+
+type reloader struct {
+	path string
+	mod  time.Time
+	keep bool // keep player position and stamina
+}
+
+func newreloader(path string, keep bool) *reloader {
+	r := &reloader{path: path, keep: keep}
+	if fi, err := os.Stat(path); err == nil {
+		r.mod = fi.ModTime()
+	}
+	return r
+}
+
+// updreload reloads the project when its file changes and restarts the
+// current level from it, with the score from before the level. A project that fails to parse or whose current
+// level doesn't load is ignored and the running level is left alone, the
+// editor may still be writing it.
+func updreload(g *game) {
+	r := g.reload
+	if r == nil || g.tick%reloadticks != 0 {
+		return
+	}
+	fi, err := os.Stat(r.path)
+	if err != nil || !fi.ModTime().After(r.mod) {
+		return
+	}
+	r.mod = fi.ModTime()
+
+	proj, err := sim.OpenProject(r.path)
+	if err != nil {
+		log.Printf("reload: %s: %v", r.path, err)
+		return
+	}
+	if g.lvl >= len(proj.Levels) {
+		log.Printf("reload: %s has no level %d anymore", r.path, g.lvl)
+		return
+	}
+	l, err := sim.Load(proj, proj.Levels[g.lvl])
+	if err != nil {
+		log.Printf("reload: %v", err)
+		return
+	}
+	g.ldtk = proj
+	if g.state != splay {
+		return
+	}
+
+	plx, ply, sta := g.w.Plx, g.w.Ply, g.w.Stamina
+	setlevel(g, g.lvl, l)
+	g.w.Newscore = g.score
+	if r.keep {
+		g.w.Plx, g.w.Ply, g.w.Stamina = plx, ply, sta
+	}
+	log.Printf("reloaded %s", g.w.Lvl.Name)
+}
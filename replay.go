@@ -0,0 +1,53 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/neputevshina/ldjam49/sim"
+)
+
+func loadreplay(path string) (*sim.Replay, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	r, err := sim.ReadReplay(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if r.Version != sim.Version {
+		log.Printf("%s: recorded with version %s, this is %s", path, r.Version, sim.Version)
+	}
+	return r, nil
+}
+
+// saverec writes the inputs of the attempt that just ended, by dying,
+// clearing or from the pause menu, to the record directory, if there is
+// one.
+func saverec(g *game) {
+	if g.recdir == "" {
+		return
+	}
+	r := &sim.Replay{
+		Version: sim.Version,
+		Lvl:     g.lvl,
+		Seed:    g.w.Seed,
+		Mode:    g.w.Phys.Mode,
+		Inputs:  g.rec,
+	}
+	name := fmt.Sprintf("%s-%d.replay", g.w.Lvl.Name, time.Now().Unix())
+	f, err := os.Create(filepath.Join(g.recdir, name))
+	if err != nil {
+		log.Print(err)
+		return
+	}
+	defer f.Close()
+	if _, err := r.WriteTo(f); err != nil {
+		log.Print(err)
+	}
+}
@@ -0,0 +1,59 @@
+// Package res is the files the game is built with and the manifest of
+// what it loads from them. It doesn't touch ebiten, the game decodes
+// the files itself.
+package res
+
+import "embed"
+
+// Everything in FS has to be listed in the manifest too.
+
+//go:embed data/atlas2.png data/player.png data/intro.png data/logo1.png
+//go:embed data/PKMN-Mystery-Dungeon.ttf data/expl.png
+//go:embed data/babah16.png data/babah32.png data/babah1632.png
+//go:embed audio/level1_music.mp3 audio/641.mp3 audio/minusears.mp3
+//go:embed audio/explode0.mp3 audio/explode1.mp3 audio/explode2.mp3
+//go:embed audio/spawn_fadeout.mp3 audio/poweron.mp3
+var FS embed.FS
+
+//go:embed data/2021.ldtk
+var Ldtk []byte // the built-in project
+
+// Kinds of assets.
+const (
+	Kimg = iota
+	Ksnd
+	Kfont
+)
+
+// Asset is a file the game loads from FS. Optional ones are replaced
+// with a placeholder of W×H pixels or with silence if they are missing
+// or broken.
+type Asset struct {
+	Path string
+	Kind int
+	Opt  bool
+	W    int
+	H    int
+}
+
+// Manifest is every asset the game loads.
+var Manifest = []Asset{
+	{Path: "data/atlas2.png", Kind: Kimg},
+	{Path: "data/player.png", Kind: Kimg},
+	{Path: "data/intro.png", Kind: Kimg, Opt: true, W: 200, H: 160},
+	{Path: "data/logo1.png", Kind: Kimg, Opt: true, W: 200, H: 160},
+	{Path: "data/expl.png", Kind: Kimg, Opt: true, W: 128, H: 96},
+	{Path: "data/babah16.png", Kind: Kimg, Opt: true, W: 16, H: 16},
+	{Path: "data/babah32.png", Kind: Kimg, Opt: true, W: 32, H: 32},
+	{Path: "data/babah1632.png", Kind: Kimg, Opt: true, W: 32, H: 16},
+	{Path: "data/PKMN-Mystery-Dungeon.ttf", Kind: Kfont},
+	{Path: "audio/menu_music.mp3", Kind: Ksnd, Opt: true},
+	{Path: "audio/level1_music.mp3", Kind: Ksnd, Opt: true},
+	{Path: "audio/explode0.mp3", Kind: Ksnd, Opt: true},
+	{Path: "audio/explode1.mp3", Kind: Ksnd, Opt: true},
+	{Path: "audio/explode2.mp3", Kind: Ksnd, Opt: true},
+	{Path: "audio/minusears.mp3", Kind: Ksnd, Opt: true},
+	{Path: "audio/641.mp3", Kind: Ksnd, Opt: true},
+	{Path: "audio/spawn_fadeout.mp3", Kind: Ksnd, Opt: true},
+	{Path: "audio/poweron.mp3", Kind: Ksnd, Opt: true},
+}
@@ -0,0 +1,35 @@
+package res
+
+import (
+	"io/fs"
+	"testing"
+)
+
+// TestManifest checks that the embed directives and the manifest list
+// the same files. Only optional assets may be missing, the game plays
+// without them.
+func TestManifest(t *testing.T) {
+	listed := make(map[string]bool)
+	for _, a := range Manifest {
+		listed[a.Path] = true
+		if _, err := fs.Stat(FS, a.Path); err != nil {
+			if a.Opt {
+				t.Logf("optional %s is not embedded", a.Path)
+				continue
+			}
+			t.Errorf("%s is in the manifest but not embedded", a.Path)
+		}
+	}
+	err := fs.WalkDir(FS, ".", func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() && !listed[path] {
+			t.Errorf("%s is embedded but not in the manifest", path)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
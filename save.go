@@ -0,0 +1,128 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/neputevshina/ldjam49/sim"
+)
+
+// savever is the version of the save file. A file of a newer version is
+// not touched, so that an older build doesn't wipe its progress.
+const savever = 1
+
+// save is the progress of the built-in levels and the settings.
+type save struct {
+	Version  int            `json:"version"`
+	Cleared  int            `json:"cleared"`  // highest level cleared, -1 if none
+	Best     map[string]int `json:"best"`     // score of a level by identifier
+	Movement string         `json:"movement"` // forced movement, "" is the level's
+
+	path string // where to write it, "" to never write
+}
+
+func newsave() *save {
+	return &save{Version: savever, Cleared: -1, Best: make(map[string]int)}
+}
+
+// savefile is where the save is, or "" if there's no config dir.
+func savefile() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "lbr", "save.json")
+}
+
+// loadsave reads the save, or starts a new one if there is none or it
+// can't be read.
+func loadsave(path string) *save {
+	s := newsave()
+	if path == "" {
+		return s
+	}
+	s.path = path
+	b, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return s
+	}
+	if err == nil {
+		err = json.Unmarshal(b, s)
+	}
+	if err == nil && s.Version > savever {
+		err = fmt.Errorf("version %d is newer than %d", s.Version, savever)
+		s.path = ""
+	}
+	if err != nil {
+		log.Printf("%s: %v", path, err)
+		s.Version, s.Cleared, s.Best = savever, -1, make(map[string]int)
+		return s
+	}
+	if s.Best == nil {
+		s.Best = make(map[string]int)
+	}
+	s.Version = savever
+	return s
+}
+
+func (s *save) write() {
+	if s.path == "" {
+		return
+	}
+	b, err := json.MarshalIndent(s, "", "\t")
+	if err == nil {
+		err = os.MkdirAll(filepath.Dir(s.path), 0755)
+	}
+	if err == nil {
+		err = os.WriteFile(s.path, b, 0644)
+	}
+	if err != nil {
+		log.Print(err)
+	}
+}
+
+// mode is the movement of the settings.
+func (s *save) mode() (m sim.Mode, force bool) {
+	if s.Movement == "" {
+		return 0, false
+	}
+	m, err := sim.ParseMode(s.Movement)
+	if err != nil {
+		log.Printf("%s: %v", s.path, err)
+		return 0, false
+	}
+	return m, true
+}
+
+func (s *save) setmode(m sim.Mode, force bool) {
+	s.Movement = ""
+	if force {
+		s.Movement = m.String()
+	}
+	s.write()
+}
+
+// cleared records that the current level is cleared with the score it
+// got in this attempt.
+func cleared(g *game) {
+	s := g.save
+	if g.lvl > s.Cleared {
+		s.Cleared = g.lvl
+	}
+	sc := g.w.Newscore - g.score
+	if sc > s.Best[g.w.Lvl.Name] {
+		s.Best[g.w.Lvl.Name] = sc
+	}
+	s.write()
+}
+
+// resume is the level Continue starts, the one after the last cleared.
+func (s *save) resume(nlevels int) int {
+	if s.Cleared+1 < nlevels {
+		return s.Cleared + 1
+	}
+	return nlevels - 1
+}
@@ -0,0 +1,81 @@
+package sim
+
+import "math"
+
+// Plrad is the radius of the ball in tiles, its sprite is 12 pixels
+// wide.
+const Plrad = 6.0 / Tilesize
+
+// overlap is how deep a ball at x, y is in walls or outside of the
+// level, 0 if it touches nothing.
+func (l *Level) overlap(x, y float64) float64 {
+	d := 0.0
+	for ty := int(math.Floor(y - Plrad)); ty <= int(math.Floor(y+Plrad)); ty++ {
+		for tx := int(math.Floor(x - Plrad)); tx <= int(math.Floor(x+Plrad)); tx++ {
+			if l.Free(tx, ty) {
+				continue
+			}
+			cx := math.Max(float64(tx), math.Min(x, float64(tx+1)))
+			cy := math.Max(float64(ty), math.Min(y, float64(ty+1)))
+			dx, dy := x-cx, y-cy
+			if p := Plrad - math.Sqrt(dx*dx+dy*dy); p > d {
+				d = p
+			}
+		}
+	}
+	return d
+}
+
+// Hits reports whether a ball at x, y is in a wall or outside of the
+// level.
+func (l *Level) Hits(x, y float64) bool {
+	return l.overlap(x, y) > 0
+}
+
+// move sweeps the ball by dx, dy in steps shorter than its radius, so it
+// can't tunnel through a wall. On contact the ball stops flush with the
+// wall along that axis. nx, ny is the normal of the walls it touched,
+// zero along an axis it moved freely. A ball that is already stuck in a
+// wall may only move out of it.
+func (w *World) move(dx, dy float64) (nx, ny float64) {
+	n := math.Ceil(math.Max(math.Abs(dx), math.Abs(dy)) / (Plrad / 2))
+	if n < 1 {
+		n = 1
+	}
+	sx, sy := dx/n, dy/n
+	for i := 0; i < int(n); i++ {
+		if sx != 0 && !w.slide(&w.Plx, sx, func(v float64) float64 { return w.Lvl.overlap(v, w.Ply) }) {
+			nx = -math.Copysign(1, sx)
+			sx = 0
+		}
+		if sy != 0 && !w.slide(&w.Ply, sy, func(v float64) float64 { return w.Lvl.overlap(w.Plx, v) }) {
+			ny = -math.Copysign(1, sy)
+			sy = 0
+		}
+	}
+	return nx, ny
+}
+
+// slide moves the coordinate c by d as far as it gets before a wall,
+// which is measured by over. It reports whether c moved all the way.
+func (w *World) slide(c *float64, d float64, over func(float64) float64) bool {
+	cur := over(*c)
+	if next := over(*c + d); next == 0 || next < cur {
+		*c += d
+		return true
+	}
+	if cur > 0 {
+		return false
+	}
+	lo, hi := 0.0, 1.0
+	for i := 0; i < 16; i++ {
+		mid := (lo + hi) / 2
+		if over(*c+d*mid) == 0 {
+			lo = mid
+		} else {
+			hi = mid
+		}
+	}
+	*c += d * lo
+	return false
+}
@@ -0,0 +1,177 @@
+package sim
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// testlevel makes a level of rows of tiles: # is a wall, o is where the
+// player starts, in the middle of the tile, anything else is free.
+func testlevel(rows ...string) *Level {
+	l := &Level{
+		Name:    "test",
+		W:       len(rows[0]),
+		H:       len(rows),
+		Stamina: 100,
+		Phys:    Defphysics,
+		Rules:   Defrules,
+	}
+	l.Coll = make([]Coll, l.W*l.H)
+	for y, r := range rows {
+		for x, c := range r {
+			switch c {
+			case '#':
+				l.Coll[y*l.W+x] = Csolid
+			case 'o':
+				l.Plx, l.Ply = float64(x)+0.5, float64(y)+0.5
+			}
+		}
+	}
+	return l
+}
+
+func hasevent(evs []Event, ev Event) bool {
+	for _, e := range evs {
+		if e == ev {
+			return true
+		}
+	}
+	return false
+}
+
+// step steps the world and checks that the ball is out of walls and
+// that the tick cost Tickcost, and Wallcost more if it hit a wall.
+func step(t *testing.T, w *World, in Input) []Event {
+	t.Helper()
+	sta := w.Stamina
+	evs := w.Step(in)
+	if w.Lvl.Hits(w.Plx, w.Ply) {
+		t.Fatalf("tick %d: ball at %.4f, %.4f is in a wall", w.Tick, w.Plx, w.Ply)
+	}
+	want := sta - Tickcost
+	if hasevent(evs, Ewall) {
+		want -= Wallcost
+	}
+	if math.Abs(w.Stamina-want) > 1e-9 {
+		t.Fatalf("tick %d: stamina %v, want %v", w.Tick, w.Stamina, want)
+	}
+	return evs
+}
+
+var jitters = []float64{0, Jitter, 0.2}
+
+func TestConcaveCorner(t *testing.T) {
+	for _, j := range jitters {
+		l := testlevel(
+			"#####",
+			"#...#",
+			"#.o.#",
+			"#...#",
+			"#####",
+		)
+		l.Phys.Jitter = j
+		w := New(l, 1)
+		walls := 0
+		maxx, maxy := 0.0, 0.0
+		for i := 0; i < 300; i++ {
+			if hasevent(step(t, w, Input{Down: true, Right: true}), Ewall) {
+				walls++
+			}
+			maxx, maxy = math.Max(maxx, w.Plx), math.Max(maxy, w.Ply)
+		}
+		if walls == 0 {
+			t.Errorf("jitter %v: never hit the corner", j)
+		}
+		if lim := 4 - Plrad - 0.01; maxx < lim || maxy < lim {
+			t.Errorf("jitter %v: got to %.3f, %.3f at most, not into the corner", j, maxx, maxy)
+		}
+	}
+}
+
+func TestConvexCorner(t *testing.T) {
+	for _, j := range jitters {
+		l := testlevel(
+			".....",
+			".o...",
+			"..#..",
+			".....",
+			".....",
+		)
+		l.Phys.Jitter = j
+		w := New(l, 1)
+		walls := 0
+		for i := 0; i < 60; i++ {
+			if hasevent(step(t, w, Input{Down: true, Right: true}), Ewall) {
+				walls++
+			}
+		}
+		if walls == 0 {
+			t.Errorf("jitter %v: never hit the corner", j)
+		}
+	}
+}
+
+// A ball pushed into a wall at an angle keeps moving along it.
+func TestSlide(t *testing.T) {
+	l := testlevel(
+		"#######",
+		"#o....#",
+		"#.....#",
+		"#######",
+	)
+	l.Phys.Jitter = 0
+	w := New(l, 1)
+	w.Ply = 1 + Plrad + 0.01
+	x := w.Plx
+	evs := step(t, w, Input{Up: true, Right: true})
+	if !hasevent(evs, Ewall) {
+		t.Fatalf("didn't hit the wall, ball at %.4f, %.4f", w.Plx, w.Ply)
+	}
+	if want := x + Speed/math.Sqrt2; math.Abs(w.Plx-want) > 1e-9 {
+		t.Errorf("x is %.4f after the hit, want %.4f", w.Plx, want)
+	}
+	if w.Vx <= 0 || w.Vy <= 0 {
+		t.Errorf("velocity %.4f, %.4f after the hit, want along the wall and away from it", w.Vx, w.Vy)
+	}
+}
+
+func TestNoJitter(t *testing.T) {
+	l := testlevel(
+		"........",
+		".o......",
+		"........",
+	)
+	l.Phys.Jitter = 0
+	w := New(l, 1)
+	for i := 0; i < 10; i++ {
+		step(t, w, Input{Right: true})
+	}
+	if math.Abs(w.Plx-(1.5+10*Speed)) > 1e-9 || w.Ply != 1.5 {
+		t.Errorf("ball at %.4f, %.4f, want %.4f, 1.5", w.Plx, w.Ply, 1.5+10*Speed)
+	}
+}
+
+// Random keys in a room with pillars never leave the ball in a wall.
+func TestJitterInWalls(t *testing.T) {
+	for _, j := range jitters {
+		l := testlevel(
+			"########",
+			"#..#...#",
+			"#.o..#.#",
+			"#.#....#",
+			"#....#.#",
+			"########",
+		)
+		l.Phys.Jitter = j
+		w := New(l, 2)
+		rng := rand.New(rand.NewSource(3))
+		in := Input{}
+		for i := 0; i < 3000; i++ {
+			if i%20 == 0 {
+				in = InputOf(byte(rng.Intn(16)))
+			}
+			step(t, w, in)
+		}
+	}
+}
@@ -0,0 +1,269 @@
+package sim
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/solarlune/ldtkgo"
+)
+
+// Sprites are the names the game has a flammable sprite for, see
+// flamimgs in gameinit.
+var Sprites = map[string]bool{
+	"":          true,
+	"Tv":        true,
+	"Wash":      true,
+	"Microwave": true,
+	"Toaster":   true,
+	"Target":    true,
+}
+
+// Deadsprites are the sprites of destroyed flammables, see deadimgs in
+// gameinit. "" is the one of the flammable's size and None is nothing.
+var Deadsprites = map[string]bool{
+	"":      true,
+	"Small": true,
+	"Wide":  true,
+	"Big":   true,
+	"None":  true,
+}
+
+// ondestroy are the values of the OnDestroy field.
+var ondestroy = map[string]uint{
+	"explode": Freg,
+	"clear":   Ftarg,
+	"none":    Fnone,
+}
+
+// Coll is what a tile does to the player.
+type Coll uint8
+
+const (
+	Cnone   Coll = iota
+	Csolid       // blocks
+	Cdamage      // drains Hazardcost every tick on it
+	Cslow        // slows down to Slowfactor
+)
+
+// collnames are the IntGrid value and tile enum identifiers of kinds of
+// collision, in lower case. An IntGrid value with any other identifier,
+// or none, is solid.
+var collnames = map[string]Coll{
+	"passable": Cnone,
+	"decor":    Cnone,
+	"none":     Cnone,
+	"solid":    Csolid,
+	"wall":     Csolid,
+	"damaging": Cdamage,
+	"damage":   Cdamage,
+	"hazard":   Cdamage,
+	"slow":     Cslow,
+}
+
+// Level is what the simulation needs from an LDtk level. Coordinates
+// are in tiles, the player's is the centre of the ball.
+type Level struct {
+	Name    string
+	W       int
+	H       int
+	Coll    []Coll
+	Plx     float64
+	Ply     float64
+	Stamina float64
+	Flams   []Flammable
+	Phys    Physics
+	Rules   Rules
+}
+
+// Kind is the collision of the tile at x, y. Outside of the level
+// there is none, see Free.
+func (l *Level) Kind(x, y int) Coll {
+	if x < 0 || y < 0 || x >= l.W || y >= l.H {
+		return Cnone
+	}
+	return l.Coll[y*l.W+x]
+}
+
+// Wall reports whether the tile at x, y blocks the player.
+func (l *Level) Wall(x, y int) bool {
+	return l.Kind(x, y) == Csolid
+}
+
+// Rate is the stamina a tile costs per tick it would take at full speed.
+func (l *Level) Rate(p Point) float64 {
+	switch l.Kind(p.X, p.Y) {
+	case Cdamage:
+		return Tickcost + Hazardcost
+	case Cslow:
+		return Tickcost / Slowfactor
+	}
+	return Tickcost
+}
+
+// Load converts a level of the LDtk project.
+func Load(proj *Project, lv *ldtkgo.Level) (*Level, error) {
+	l := &Level{
+		Name: lv.Identifier,
+		W:    lv.Width / Tilesize,
+		H:    lv.Height / Tilesize,
+	}
+	l.loadcoll(proj, lv)
+	ph, err := loadphysics(lv)
+	if err != nil {
+		return nil, fmt.Errorf("level %s: %w", lv.Identifier, err)
+	}
+	l.Phys = ph
+	if l.Rules, err = loadrules(lv); err != nil {
+		return nil, fmt.Errorf("level %s: %w", lv.Identifier, err)
+	}
+
+	ent := lv.LayerByIdentifier("Entities")
+	if ent == nil {
+		return nil, fmt.Errorf("no entities in level %s", lv.Identifier)
+	}
+	pl := ent.EntityByIdentifier("Player")
+	if pl == nil {
+		return nil, fmt.Errorf("no player in level %s", lv.Identifier)
+	}
+	st := pl.PropertyByIdentifier("Stamina")
+	if st == nil || st.IsNull() {
+		return nil, fmt.Errorf("player has no stamina in level %s", lv.Identifier)
+	}
+	l.Stamina = st.AsFloat64()
+	l.Plx = float64(pl.Position[0]) / Tilesize
+	l.Ply = float64(pl.Position[1]) / Tilesize
+	l.Flams = parseflams(ent.Entities)
+	return l, nil
+}
+
+// loadcoll reads the collision from the AutoWalls IntGrid layer. The
+// kind of a cell comes from the identifier of its IntGrid value, and an
+// enum tag of the tile drawn over it in the tileset overrides that.
+func (l *Level) loadcoll(proj *Project, lv *ldtkgo.Level) {
+	l.Coll = make([]Coll, l.W*l.H)
+	walls := lv.LayerByIdentifier("AutoWalls")
+	if walls == nil {
+		return
+	}
+	set := func(px, py int, k Coll) {
+		x, y := walls.ToGridPosition(px, py)
+		if x >= 0 && y >= 0 && x < l.W && y < l.H {
+			l.Coll[y*l.W+x] = k
+		}
+	}
+	names := proj.intgrid[walls.Identifier]
+	for _, v := range walls.IntGrid {
+		k := Csolid
+		if c, ok := collnames[strings.ToLower(names[v.Value])]; ok {
+			k = c
+		}
+		set(v.Position[0], v.Position[1], k)
+	}
+	if walls.Tileset == nil {
+		return
+	}
+	for _, t := range walls.AllTiles() {
+		for _, e := range walls.Tileset.EnumsForTile(t.ID) {
+			if c, ok := collnames[strings.ToLower(e)]; ok {
+				set(t.Position[0], t.Position[1], c)
+			}
+		}
+	}
+}
+
+// FindLevel resolves a level given by its index or its identifier.
+func FindLevel(proj *Project, s string) (int, error) {
+	if n, err := strconv.Atoi(s); err == nil {
+		if n < 0 || n >= len(proj.Levels) {
+			return 0, fmt.Errorf("no level %d, there are %d", n, len(proj.Levels))
+		}
+		return n, nil
+	}
+	for i, l := range proj.Levels {
+		if l.Identifier == s {
+			return i, nil
+		}
+	}
+	return 0, fmt.Errorf("no level %s", s)
+}
+
+func parseflams(ent []*ldtkgo.Entity) []Flammable {
+	fls := make([]Flammable, 0, 20)
+	for _, e := range ent {
+		if e.Identifier != "Player" {
+			fl, _ := flamof(e)
+			fls = append(fls, fl)
+		}
+	}
+	return fls
+}
+
+// flamof makes a flammable of any entity from its fields Durability,
+// ScorePerTick, DrainRadius, Sprite (or Type), DeadSprite, OnDestroy and
+// Rot. What is missing is as in the jam version, where a Target is
+// cleared on touch and everything else is drained by its size. A field
+// that is wrong is a problem and the default is used instead.
+func flamof(e *ldtkgo.Entity) (Flammable, []string) {
+	var errs []string
+	prop := func(name string) interface{} {
+		if p := e.PropertyByIdentifier(name); p != nil {
+			return p.Value
+		}
+		return nil
+	}
+	num := func(name string, def float64) float64 {
+		switch v := prop(name).(type) {
+		case nil:
+		case float64:
+			if v >= 0 || name == "Rot" {
+				return v
+			}
+			errs = append(errs, fmt.Sprintf("%s is %v", name, v))
+		default:
+			errs = append(errs, fmt.Sprintf("%s is not a number", name))
+		}
+		return def
+	}
+	str := func(name string, def string) string {
+		switch v := prop(name).(type) {
+		case nil:
+		case string:
+			return v
+		default:
+			errs = append(errs, fmt.Sprintf("%s is not a string", name))
+		}
+		return def
+	}
+
+	fl := Flammable{
+		W: uint(e.Width / Tilesize),
+		H: uint(e.Height / Tilesize),
+		X: float64(e.Position[0]) / Tilesize,
+		Y: float64(e.Position[1]) / Tilesize,
+	}
+	dur, od, spr := float64(fl.W*fl.H)*Tileprice, "explode", e.Identifier
+	if e.Identifier == "Target" {
+		dur, od, spr = 0, "clear", "Target"
+	}
+	fl.Rot = int(num("Rot", 0))
+	fl.Dur = num("Durability", dur)
+	fl.Score = int(num("ScorePerTick", 5))
+	fl.Rad = num("DrainRadius", fl.Radius())
+	fl.Sprite = str("Sprite", str("Type", spr))
+	if !Sprites[fl.Sprite] {
+		errs = append(errs, fmt.Sprintf("no sprite %q", fl.Sprite))
+	}
+	fl.Deadsprite = str("DeadSprite", "")
+	if !Deadsprites[fl.Deadsprite] {
+		errs = append(errs, fmt.Sprintf("no dead sprite %q", fl.Deadsprite))
+	}
+	fl.Typ = ondestroy[od]
+	v := str("OnDestroy", od)
+	if t, ok := ondestroy[strings.ToLower(v)]; ok {
+		fl.Typ = t
+	} else {
+		errs = append(errs, fmt.Sprintf("OnDestroy is %q, not explode, clear or none", v))
+	}
+	return fl, errs
+}
@@ -0,0 +1,90 @@
+package sim
+
+import (
+	"container/heap"
+	"math"
+)
+
+// Point is a tile of the level.
+type Point struct {
+	X int
+	Y int
+}
+
+// Free reports whether the player can be on the tile.
+func (l *Level) Free(x, y int) bool {
+	return x >= 0 && y >= 0 && x < l.W && y < l.H && !l.Wall(x, y)
+}
+
+// Path finds the cheapest way over free tiles from the tile from to any
+// tile where goal is true. The player moves through tile centres in eight
+// directions without cutting wall corners; rate is the stamina a tick on
+// the tile costs. The returned path starts with from, ok is false if no
+// goal tile can be reached.
+func (l *Level) Path(from Point, goal func(Point) bool, rate func(Point) float64) (path []Point, cost float64, ok bool) {
+	if !l.Free(from.X, from.Y) {
+		return nil, 0, false
+	}
+	idx := func(p Point) int { return p.Y*l.W + p.X }
+	dist := make([]float64, l.W*l.H)
+	prev := make([]int, l.W*l.H)
+	for i := range dist {
+		dist[i] = math.Inf(1)
+		prev[i] = -1
+	}
+	dist[idx(from)] = 0
+	q := &pathq{{from, 0}}
+	for q.Len() > 0 {
+		it := heap.Pop(q).(pathitem)
+		p := it.p
+		if it.d > dist[idx(p)] {
+			continue
+		}
+		if goal(p) {
+			for i := idx(p); i >= 0; i = prev[i] {
+				path = append(path, Point{i % l.W, i / l.W})
+			}
+			for i, j := 0, len(path)-1; i < j; i, j = i+1, j-1 {
+				path[i], path[j] = path[j], path[i]
+			}
+			return path, it.d, true
+		}
+		for dy := -1; dy <= 1; dy++ {
+			for dx := -1; dx <= 1; dx++ {
+				n := Point{p.X + dx, p.Y + dy}
+				if n == p || !l.Free(n.X, n.Y) {
+					continue
+				}
+				if dx != 0 && dy != 0 && (!l.Free(p.X+dx, p.Y) || !l.Free(p.X, p.Y+dy)) {
+					continue
+				}
+				ticks := math.Hypot(float64(dx), float64(dy)) / Speed
+				d := it.d + ticks*(rate(p)+rate(n))/2
+				if d < dist[idx(n)] {
+					dist[idx(n)] = d
+					prev[idx(n)] = idx(p)
+					heap.Push(q, pathitem{n, d})
+				}
+			}
+		}
+	}
+	return nil, 0, false
+}
+
+type pathitem struct {
+	p Point
+	d float64
+}
+
+type pathq []pathitem
+
+func (q pathq) Len() int            { return len(q) }
+func (q pathq) Less(i, j int) bool  { return q[i].d < q[j].d }
+func (q pathq) Swap(i, j int)       { q[i], q[j] = q[j], q[i] }
+func (q *pathq) Push(x interface{}) { *q = append(*q, x.(pathitem)) }
+func (q *pathq) Pop() interface{} {
+	old := *q
+	it := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return it
+}
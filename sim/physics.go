@@ -0,0 +1,108 @@
+package sim
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/solarlune/ldtkgo"
+)
+
+// Mode is how the controls move the ball.
+type Mode uint8
+
+const (
+	Minstant  Mode = iota // full speed right away, as in the jam version
+	Mmomentum             // accelerates up to a top speed, friction slows down
+)
+
+var modenames = []string{"instant", "momentum"}
+
+func (m Mode) String() string {
+	if int(m) < len(modenames) {
+		return modenames[m]
+	}
+	return fmt.Sprint("mode", uint8(m))
+}
+
+// ParseMode is the inverse of Mode.String.
+func ParseMode(s string) (Mode, error) {
+	for i, n := range modenames {
+		if n == s {
+			return Mode(i), nil
+		}
+	}
+	return 0, fmt.Errorf("unknown movement %q", s)
+}
+
+// Physics are the movement parameters of a level. Levels set them with
+// the fields Movement, Accel, Friction, TopSpeed and Jitter.
+type Physics struct {
+	Mode     Mode
+	Accel    float64 // tiles per tick² while a key is held
+	Friction float64 // part of the speed kept every tick
+	Top      float64 // tiles per tick
+	Jitter   float64 // tiles per tick at most, randomly
+}
+
+// Defphysics is what a level without physics fields gets.
+var Defphysics = Physics{
+	Mode:     Minstant,
+	Accel:    0.006,
+	Friction: 0.93,
+	Top:      0.07,
+	Jitter:   Jitter,
+}
+
+// physfields are the level fields of Physics and where they go.
+func physfields(ph *Physics) map[string]*float64 {
+	return map[string]*float64{
+		"Accel":    &ph.Accel,
+		"Friction": &ph.Friction,
+		"TopSpeed": &ph.Top,
+		"Jitter":   &ph.Jitter,
+	}
+}
+
+func loadphysics(lv *ldtkgo.Level) (Physics, error) {
+	ph := Defphysics
+	if p := lv.PropertyByIdentifier("Movement"); p != nil && !p.IsNull() {
+		s, _ := p.Value.(string)
+		m, err := ParseMode(s)
+		if err != nil {
+			return ph, err
+		}
+		ph.Mode = m
+	}
+	for name, f := range physfields(&ph) {
+		p := lv.PropertyByIdentifier(name)
+		if p == nil || p.IsNull() {
+			continue
+		}
+		v, ok := p.Value.(float64)
+		if !ok {
+			return ph, fmt.Errorf("%s is not a number", name)
+		}
+		*f = v
+	}
+	if ph.Friction < 0 || ph.Friction >= 1 {
+		return ph, errors.New("Friction must be from 0 up to 1")
+	}
+	return ph, nil
+}
+
+// accel is the velocity the controls make in momentum mode.
+func (w *World) accel(in Input) (vx, vy float64) {
+	ph := &w.Phys
+	ax, ay := w.steer(in, ph.Accel)
+	vx = (w.Vx + ax) * ph.Friction
+	vy = (w.Vy + ay) * ph.Friction
+	top := ph.Top * w.boost()
+	if w.on() == Cslow {
+		top *= Slowfactor
+	}
+	if v := math.Sqrt(vx*vx + vy*vy); v > top {
+		vx, vy = vx*top/v, vy*top/v
+	}
+	return vx, vy
+}
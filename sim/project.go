@@ -0,0 +1,65 @@
+package sim
+
+import (
+	"encoding/json"
+	"os"
+
+	"github.com/solarlune/ldtkgo"
+)
+
+// Project is an LDtk project with the layer definitions ldtkgo leaves
+// out.
+type Project struct {
+	*ldtkgo.Project
+
+	// intgrid are the identifiers of IntGrid values by layer and value.
+	intgrid map[string]map[int]string
+}
+
+// ReadProject parses an LDtk project.
+func ReadProject(data []byte) (*Project, error) {
+	lp, err := ldtkgo.Read(data)
+	if err != nil {
+		return nil, err
+	}
+	var defs struct {
+		Defs struct {
+			Layers []struct {
+				Identifier string
+				Type       string
+				Values     []struct {
+					Value      int
+					Identifier string
+				} `json:"intGridValues"`
+			}
+		}
+	}
+	if err := json.Unmarshal(data, &defs); err != nil {
+		return nil, err
+	}
+	p := &Project{Project: lp, intgrid: make(map[string]map[int]string)}
+	for _, l := range defs.Defs.Layers {
+		if l.Type != "IntGrid" {
+			continue
+		}
+		vs := make(map[int]string)
+		for i, v := range l.Values {
+			// Before LDtk 0.8 values had no number of their own.
+			if v.Value == 0 {
+				v.Value = i + 1
+			}
+			vs[v.Value] = v.Identifier
+		}
+		p.intgrid[l.Identifier] = vs
+	}
+	return p, nil
+}
+
+// OpenProject reads an LDtk project file.
+func OpenProject(path string) (*Project, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ReadProject(data)
+}
@@ -0,0 +1,29 @@
+package sim
+
+import "testing"
+
+// IntGrid value identifiers are by layer and by value, not by place in
+// one list of all layers.
+func TestIntGridNames(t *testing.T) {
+	p, err := ReadProject([]byte(`{"levels": [], "defs": {"layers": [
+		{"identifier": "Other", "type": "IntGrid", "intGridValues": [
+			{"value": 1, "identifier": "Slow"}
+		]},
+		{"identifier": "AutoWalls", "type": "IntGrid", "intGridValues": [
+			{"value": 3, "identifier": "Damaging"},
+			{"value": 1, "identifier": "Wall"},
+			{"value": 7, "identifier": null}
+		]}
+	]}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for v, want := range map[int]string{1: "Wall", 3: "Damaging", 7: "", 2: ""} {
+		if got := p.intgrid["AutoWalls"][v]; got != want {
+			t.Errorf("AutoWalls value %d is %q, want %q", v, got, want)
+		}
+	}
+	if got := p.intgrid["Other"][1]; got != "Slow" {
+		t.Errorf("Other value 1 is %q, want Slow", got)
+	}
+}
@@ -0,0 +1,183 @@
+package sim
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Replay is a recorded run of one level: everything needed to play it
+// again tick by tick.
+//
+// On disk it is the magic "LBRR", a format byte, the game version as a
+// length-prefixed string, then the level index and the seed as varints,
+// the movement mode byte and the number of ticks. The inputs follow
+// run-length encoded: an input byte, the stick as two bytes if the input
+// byte says there is one, and a uvarint run length. Format 1 has no
+// movement mode, it is always instant.
+type Replay struct {
+	Version string
+	Lvl     int
+	Seed    int64
+	Mode    Mode
+	Inputs  []Input
+}
+
+// Version goes into replays so that a replay from a build with other
+// rules can be told apart.
+const Version = "1.5"
+
+const (
+	replaymagic  = "LBRR"
+	replayformat = 3
+)
+
+// stickbit in Input.Bits means there is an analog stick.
+const stickbit = 1 << 4
+
+// Bits packs the keys into the low four bits of a byte, WASD order.
+func (in Input) Bits() byte {
+	var b byte
+	for i, k := range [...]bool{in.Up, in.Left, in.Down, in.Right} {
+		if k {
+			b |= 1 << i
+		}
+	}
+	if in.Sx != 0 || in.Sy != 0 {
+		b |= stickbit
+	}
+	return b
+}
+
+// InputOf is the inverse of Input.Bits, without the stick.
+func InputOf(b byte) Input {
+	return Input{
+		Up:    b&1 != 0,
+		Left:  b&2 != 0,
+		Down:  b&4 != 0,
+		Right: b&8 != 0,
+	}
+}
+
+// Input returns the input of the tick, or no input past the end of the
+// recording.
+func (r *Replay) Input(tick uint) Input {
+	if tick >= uint(len(r.Inputs)) {
+		return Input{}
+	}
+	return r.Inputs[tick]
+}
+
+func (r *Replay) WriteTo(w io.Writer) (int64, error) {
+	bw := bufio.NewWriter(w)
+	buf := make([]byte, binary.MaxVarintLen64)
+	n := int64(0)
+	put := func(b []byte) {
+		k, _ := bw.Write(b)
+		n += int64(k)
+	}
+	uvar := func(v uint64) {
+		put(buf[:binary.PutUvarint(buf, v)])
+	}
+
+	put([]byte(replaymagic))
+	put([]byte{replayformat})
+	uvar(uint64(len(r.Version)))
+	put([]byte(r.Version))
+	uvar(uint64(r.Lvl))
+	put(buf[:binary.PutVarint(buf, r.Seed)])
+	put([]byte{byte(r.Mode)})
+	uvar(uint64(len(r.Inputs)))
+	for i := 0; i < len(r.Inputs); {
+		in := r.Inputs[i]
+		j := i + 1
+		for j < len(r.Inputs) && r.Inputs[j] == in {
+			j++
+		}
+		b := in.Bits()
+		put([]byte{b})
+		if b&stickbit != 0 {
+			put([]byte{byte(in.Sx), byte(in.Sy)})
+		}
+		uvar(uint64(j - i))
+		i = j
+	}
+	return n, bw.Flush()
+}
+
+// ReadReplay decodes a replay written by Replay.WriteTo.
+func ReadReplay(rd io.Reader) (*Replay, error) {
+	br := bufio.NewReader(rd)
+	hdr := make([]byte, len(replaymagic)+1)
+	if _, err := io.ReadFull(br, hdr); err != nil {
+		return nil, fmt.Errorf("replay header: %w", err)
+	}
+	if string(hdr[:len(replaymagic)]) != replaymagic {
+		return nil, errors.New("not a replay file")
+	}
+	format := hdr[len(replaymagic)]
+	if format < 1 || format > replayformat {
+		return nil, fmt.Errorf("unknown replay format %d", format)
+	}
+
+	r := &Replay{}
+	vl, err := binary.ReadUvarint(br)
+	if err != nil {
+		return nil, fmt.Errorf("replay version: %w", err)
+	}
+	if vl > 256 {
+		return nil, errors.New("replay version too long")
+	}
+	v := make([]byte, vl)
+	if _, err := io.ReadFull(br, v); err != nil {
+		return nil, fmt.Errorf("replay version: %w", err)
+	}
+	r.Version = string(v)
+	lvl, err := binary.ReadUvarint(br)
+	if err != nil {
+		return nil, fmt.Errorf("replay level: %w", err)
+	}
+	r.Lvl = int(lvl)
+	if r.Seed, err = binary.ReadVarint(br); err != nil {
+		return nil, fmt.Errorf("replay seed: %w", err)
+	}
+	if format >= 2 {
+		m, err := br.ReadByte()
+		if err != nil {
+			return nil, fmt.Errorf("replay movement: %w", err)
+		}
+		r.Mode = Mode(m)
+	}
+	ticks, err := binary.ReadUvarint(br)
+	if err != nil {
+		return nil, fmt.Errorf("replay length: %w", err)
+	}
+
+	for uint64(len(r.Inputs)) < ticks {
+		b, err := br.ReadByte()
+		if err != nil {
+			return nil, fmt.Errorf("replay inputs: %w", err)
+		}
+		in := InputOf(b)
+		if b&stickbit != 0 {
+			var st [2]byte
+			if _, err := io.ReadFull(br, st[:]); err != nil {
+				return nil, fmt.Errorf("replay inputs: %w", err)
+			}
+			in.Sx, in.Sy = int8(st[0]), int8(st[1])
+		}
+		run, err := binary.ReadUvarint(br)
+		if err != nil {
+			return nil, fmt.Errorf("replay inputs: %w", err)
+		}
+		if run == 0 || uint64(len(r.Inputs))+run > ticks {
+			return nil, errors.New("replay inputs are corrupted")
+		}
+		for ; run > 0; run-- {
+			r.Inputs = append(r.Inputs, in)
+		}
+	}
+	return r, nil
+}
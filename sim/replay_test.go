@@ -0,0 +1,121 @@
+package sim
+
+import (
+	"bytes"
+	"math"
+	"reflect"
+	"testing"
+)
+
+// run is n ticks of the same input.
+func run(in Input, n int) []Input {
+	ins := make([]Input, n)
+	for i := range ins {
+		ins[i] = in
+	}
+	return ins
+}
+
+func encode(t *testing.T, r *Replay) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	n, err := r.WriteTo(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != int64(buf.Len()) {
+		t.Errorf("WriteTo says it wrote %d bytes, it wrote %d", n, buf.Len())
+	}
+	return buf.Bytes()
+}
+
+func TestReplayRoundTrip(t *testing.T) {
+	var long []Input
+	long = append(long, run(Input{Up: true}, 1000)...) // a run over one byte
+	long = append(long, run(Input{}, 1)...)
+	long = append(long, run(Input{Down: true, Right: true}, 128)...)
+	long = append(long, run(Input{Up: true}, 1)...)
+
+	var sticks []Input
+	sticks = append(sticks, run(Input{Sx: 127}, 3)...)
+	sticks = append(sticks, run(Input{Sx: -127, Sy: -128}, 200)...)
+	sticks = append(sticks, run(Input{Sy: 1}, 1)...)
+	sticks = append(sticks, run(Input{Left: true}, 2)...)
+	sticks = append(sticks, run(Input{Left: true, Sx: -60}, 2)...)
+
+	for _, r := range []Replay{
+		{Version: Version, Lvl: 3, Seed: 42, Inputs: long},
+		{Version: Version, Lvl: 0, Seed: -1, Mode: Mmomentum, Inputs: sticks},
+		{Version: "", Lvl: 1 << 20, Seed: math.MinInt64},
+		{Version: "0.9", Lvl: 7, Seed: math.MaxInt64, Inputs: run(Input{Right: true}, 1)},
+	} {
+		got, err := ReadReplay(bytes.NewReader(encode(t, &r)))
+		if err != nil {
+			t.Errorf("seed %d: %v", r.Seed, err)
+			continue
+		}
+		if len(got.Inputs) == 0 {
+			got.Inputs = nil
+		}
+		if !reflect.DeepEqual(*got, r) {
+			t.Errorf("seed %d: read back %q, level %d, seed %d, %v, %d inputs, not the same",
+				r.Seed, got.Version, got.Lvl, got.Seed, got.Mode, len(got.Inputs))
+		}
+	}
+}
+
+// Format 1 has no movement mode, its replays are all instant.
+func TestReplayFormat1(t *testing.T) {
+	b := []byte("LBRR\x01")
+	b = append(b, 3, '1', '.', '0') // version
+	b = append(b, 2)                // level
+	b = append(b, 3)                // seed -2 as a varint
+	b = append(b, 5)                // ticks
+	b = append(b, 1, 3)             // up for 3 ticks
+	b = append(b, 0, 2)             // nothing for 2
+	r, err := ReadReplay(bytes.NewReader(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Replay{
+		Version: "1.0",
+		Lvl:     2,
+		Seed:    -2,
+		Mode:    Minstant,
+		Inputs:  append(run(Input{Up: true}, 3), run(Input{}, 2)...),
+	}
+	if !reflect.DeepEqual(*r, want) {
+		t.Errorf("read %+v, want %+v", *r, want)
+	}
+}
+
+func TestReplayBroken(t *testing.T) {
+	r := &Replay{
+		Version: Version,
+		Lvl:     1,
+		Seed:    -5,
+		Mode:    Mmomentum,
+		Inputs:  append(run(Input{Up: true}, 300), run(Input{Sx: 10, Sy: -10}, 2)...),
+	}
+	b := encode(t, r)
+	for n := 0; n < len(b); n++ {
+		if _, err := ReadReplay(bytes.NewReader(b[:n])); err == nil {
+			t.Errorf("replay cut to %d of %d bytes read without an error", n, len(b))
+		}
+	}
+
+	hdr := []byte("LBRR\x03\x00\x00\x00\x00")
+	for name, b := range map[string][]byte{
+		"magic":        []byte("LBRX\x03\x00\x00\x00\x00\x00"),
+		"format 0":     []byte("LBRR\x00\x00\x00\x00\x00\x00"),
+		"format 9":     []byte("LBRR\x09\x00\x00\x00\x00\x00"),
+		"long version": []byte("LBRR\x03\xff\x7f"),
+		"empty run":    append(append([]byte{}, hdr...), 2, 1, 0),
+		"long run":     append(append([]byte{}, hdr...), 2, 1, 3),
+		"runs past":    append(append([]byte{}, hdr...), 2, 1, 1, 0, 2),
+	} {
+		if _, err := ReadReplay(bytes.NewReader(b)); err == nil {
+			t.Errorf("%s: read without an error", name)
+		}
+	}
+}
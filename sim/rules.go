@@ -0,0 +1,188 @@
+package sim
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/solarlune/ldtkgo"
+)
+
+// Rules are how draining and destroying devices work in a level. Levels
+// set them with the fields listed in rulefields.
+type Rules struct {
+	Drain   float64 // stamina and durability per tick at the centre of a device
+	Falloff float64 // part of Drain lost at the edge of the reach
+	Split   bool    // Drain is shared between all devices in reach
+
+	Blast      float64 // radius of the blast of a destroyed device, in tiles
+	Blastdmg   float64 // durability the blast takes from devices in it
+	Blastbonus int     // score per device set off, times its place in the chain
+	Blastdelay int     // ticks from destruction to the blast
+
+	Sight bool // devices are only drained in line of sight
+
+	// With Charge the ball gains Gain stamina for every bit of durability
+	// it drains instead of losing it. Stamina over what the level starts
+	// with overcharges the ball for Overticks: it is Overspeed times as
+	// fast and walls and hazards don't hurt it.
+	Charge    bool
+	Gain      float64
+	Overticks int
+	Overspeed float64
+}
+
+// Defrules is what a level without rule fields gets.
+var Defrules = Rules{
+	Drain:   Draincost,
+	Falloff: 0.5,
+	Split:   true,
+
+	Blast:      2,
+	Blastdmg:   0.5,
+	Blastbonus: 10,
+	Blastdelay: 6,
+
+	Gain:      1,
+	Overticks: 180,
+	Overspeed: 1.5,
+}
+
+// rulefields are the level fields of Rules and where they go.
+func rulefields(r *Rules) map[string]interface{} {
+	return map[string]interface{}{
+		"Drain":        &r.Drain,
+		"DrainFalloff": &r.Falloff,
+		"SplitDrain":   &r.Split,
+		"BlastRadius":  &r.Blast,
+		"BlastDamage":  &r.Blastdmg,
+		"BlastBonus":   &r.Blastbonus,
+		"BlastDelay":   &r.Blastdelay,
+		"LineOfSight":  &r.Sight,
+
+		"Charge":          &r.Charge,
+		"ChargeGain":      &r.Gain,
+		"OverchargeTicks": &r.Overticks,
+		"OverchargeSpeed": &r.Overspeed,
+	}
+}
+
+func loadrules(lv *ldtkgo.Level) (Rules, error) {
+	r := Defrules
+	for name, f := range rulefields(&r) {
+		p := lv.PropertyByIdentifier(name)
+		if p == nil || p.IsNull() {
+			continue
+		}
+		if b, ok := f.(*bool); ok {
+			if *b, ok = p.Value.(bool); !ok {
+				return r, fmt.Errorf("%s is not a boolean", name)
+			}
+			continue
+		}
+		v, ok := p.Value.(float64)
+		if !ok {
+			return r, fmt.Errorf("%s is not a number", name)
+		}
+		if v < 0 {
+			return r, fmt.Errorf("%s is %v", name, v)
+		}
+		switch f := f.(type) {
+		case *float64:
+			*f = v
+		case *int:
+			*f = int(v)
+		}
+	}
+	if r.Drain == 0 {
+		return r, errors.New("Drain must be more than 0")
+	}
+	if r.Falloff >= 1 {
+		return r, errors.New("DrainFalloff must be less than 1")
+	}
+	return r, nil
+}
+
+// strength is the part of Drain the flammable gets from the player at
+// x, y, before it is split.
+func (r *Rules) strength(f *Flammable, x, y float64) float64 {
+	cx, cy := f.Centre()
+	return 1 - r.Falloff*math.Min(math.Hypot(cx-x, cy-y)/f.Radius(), 1)
+}
+
+// blast sets off the devices destroyed Blastdelay ticks ago. Devices in
+// the blast lose Blastdmg and, if that destroys them, blast in turn. The
+// blasts go off in waves, all of one wave before any device it destroys,
+// so a chain is always the same whatever the order of the flammables. A
+// device destroyed with a Blastdelay of 0 blasts in the next wave of the
+// same tick. Targets are not hurt, the level is only cleared by touching
+// them.
+func (w *World) blast(evs []Event) []Event {
+	r := &w.Rules
+	if r.Blast <= 0 {
+		return evs
+	}
+	done := make([]bool, len(w.Flams))
+	for {
+		var wave []int
+		for i := range w.Flams {
+			e := &w.Flams[i]
+			if done[i] || !e.Dead || e.Deadt != r.Blastdelay || (e.Typ != Freg && e.Typ != Ftoas) {
+				continue
+			}
+			done[i] = true
+			wave = append(wave, i)
+		}
+		if len(wave) == 0 {
+			return evs
+		}
+		for _, i := range wave {
+			e := &w.Flams[i]
+			ex, ey := e.Centre()
+			for j := range w.Flams {
+				f := &w.Flams[j]
+				if f.Dead || f.Typ == Ftarg {
+					continue
+				}
+				fx, fy := f.Centre()
+				if math.Hypot(fx-ex, fy-ey) > r.Blast {
+					continue
+				}
+				f.Dur -= r.Blastdmg
+				if f.Dur > 0 {
+					continue
+				}
+				f.Dead = true
+				f.Chain = e.Chain + 1
+				w.Newscore += r.Blastbonus * f.Chain
+				if f.Typ != Fnone {
+					evs = append(evs, Eexpl)
+				}
+			}
+		}
+	}
+}
+
+// overcharge turns stamina over Origsta into overcharge.
+func (w *World) overcharge(evs []Event) []Event {
+	if w.Over > 0 {
+		w.Over--
+	}
+	if !w.Rules.Charge || w.Stamina <= w.Origsta {
+		return evs
+	}
+	w.Stamina = w.Origsta
+	if w.Over == 0 {
+		evs = append(evs, Eover)
+	}
+	w.Over = uint(w.Rules.Overticks)
+	return evs
+}
+
+// boost is how many times faster the ball is now.
+func (w *World) boost() float64 {
+	if w.Over > 0 {
+		return w.Rules.Overspeed
+	}
+	return 1
+}
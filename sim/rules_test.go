@@ -0,0 +1,329 @@
+package sim
+
+import (
+	"math"
+	"testing"
+)
+
+// chainlevel has a row of devices a blast apart, a target next to them
+// and one device too far away. The player starts on the first device,
+// which has no durability left, and only reaches that one.
+func chainlevel() *Level {
+	l := testlevel(
+		"o.........",
+		"..........",
+		"..........",
+	)
+	l.Phys.Jitter = 0
+	dev := func(x, y, dur float64) Flammable {
+		return Flammable{Typ: Freg, W: 1, H: 1, X: x, Y: y, Dur: dur, Rad: 0.5}
+	}
+	l.Flams = []Flammable{
+		dev(0, 0, 0),      // drained
+		dev(1.5, 0, 0.25), // 1.5 from the first
+		dev(3, 0, 0.25),   // 1.5 from the second
+		dev(6.5, 0, 0.25), // out of reach of all
+		{Typ: Ftarg, W: 1, H: 1, X: 1.5, Y: 1, Rad: 0.5},
+	}
+	return l
+}
+
+// deaths runs the level and returns the tick every flammable died on,
+// -1 if it didn't.
+func deaths(t *testing.T, l *Level, ticks int) ([]int, *World) {
+	t.Helper()
+	w := New(l, 1)
+	dt := make([]int, len(w.Flams))
+	for i := range dt {
+		dt[i] = -1
+	}
+	for n := 0; n < ticks; n++ {
+		w.Step(Input{})
+		for i, f := range w.Flams {
+			if f.Dead && dt[i] < 0 {
+				dt[i] = n
+			}
+		}
+	}
+	return dt, w
+}
+
+func TestChain(t *testing.T) {
+	l := chainlevel()
+	dt, w := deaths(t, l, 60)
+	d := l.Rules.Blastdelay
+	want := []int{0, d, 2 * d, -1, -1}
+	for i := range want {
+		if dt[i] != want[i] {
+			t.Errorf("flammable %d died on tick %d, want %d", i, dt[i], want[i])
+		}
+	}
+	for i, c := range []int{0, 1, 2} {
+		if w.Flams[i].Chain != c {
+			t.Errorf("flammable %d is %d down the chain, want %d", i, w.Flams[i].Chain, c)
+		}
+	}
+	if want := l.Rules.Blastbonus*1 + l.Rules.Blastbonus*2; w.Newscore != want {
+		t.Errorf("score %d, want %d", w.Newscore, want)
+	}
+}
+
+// A blast over a target doesn't clear the level or hurt the target.
+func TestBlastSparesTarget(t *testing.T) {
+	l := chainlevel()
+	_, w := deaths(t, l, 60)
+	targ := w.Flams[4]
+	if targ.Dead || targ.Dur != 0 || w.Clear {
+		t.Errorf("target dead %v, durability %v, level clear %v after the blasts", targ.Dead, targ.Dur, w.Clear)
+	}
+}
+
+// The chain doesn't depend on the order the level lists devices in, also
+// when devices blast on the tick they are destroyed.
+func TestChainOrder(t *testing.T) {
+	for _, delay := range []int{Defrules.Blastdelay, 0} {
+		l := chainlevel()
+		l.Rules.Blastdelay = delay
+		dt, _ := deaths(t, l, 60)
+		if want := 2 * delay; dt[2] != want {
+			t.Errorf("delay %d: the last of the chain died on tick %d, want %d", delay, dt[2], want)
+		}
+		n := len(l.Flams)
+		for i := 0; i < n/2; i++ {
+			l.Flams[i], l.Flams[n-1-i] = l.Flams[n-1-i], l.Flams[i]
+		}
+		rdt, _ := deaths(t, l, 60)
+		for i := range dt {
+			if rdt[n-1-i] != dt[i] {
+				t.Errorf("delay %d: reversed, flammable %d died on tick %d, want %d", delay, i, rdt[n-1-i], dt[i])
+			}
+		}
+	}
+}
+
+func TestNoBlast(t *testing.T) {
+	l := chainlevel()
+	l.Rules.Blast = 0
+	dt, w := deaths(t, l, 60)
+	if dt[1] >= 0 || w.Newscore != 0 {
+		t.Errorf("with no blast radius the second device died on tick %d, score %d", dt[1], w.Newscore)
+	}
+}
+
+// drainlevel is an open level with the player in the middle, at 5.5,
+// 5.5, and devices of radius 2 centred at the points given.
+func drainlevel(at ...[2]float64) *Level {
+	l := testlevel(
+		"...........",
+		"...........",
+		"...........",
+		"...........",
+		"...........",
+		".....o.....",
+		"...........",
+		"...........",
+		"...........",
+		"...........",
+		"...........",
+	)
+	l.Phys.Jitter = 0
+	for _, c := range at {
+		l.Flams = append(l.Flams, Flammable{
+			Typ: Fnone, W: 1, H: 1, X: c[0] - 0.5, Y: c[1] - 0.5, Dur: 100, Score: 1, Rad: 2,
+		})
+	}
+	return l
+}
+
+// drained is what every flammable lost in the tick, in parts of Drain.
+func drained(w *World) []float64 {
+	durs := make([]float64, len(w.Flams))
+	for i, f := range w.Flams {
+		durs[i] = f.Dur
+	}
+	w.Step(Input{})
+	for i, f := range w.Flams {
+		durs[i] = (durs[i] - f.Dur) / w.Rules.Drain
+	}
+	return durs
+}
+
+func TestFalloff(t *testing.T) {
+	for _, c := range []struct {
+		falloff float64
+		dist    float64
+		want    float64
+	}{
+		{0.5, 0, 1},
+		{0.5, 1, 0.75},
+		{0.5, 2, 0.5}, // the edge of the reach
+		{0.5, 2.01, 0},
+		{0, 1.5, 1},
+		{0.9, 2, 0.1},
+	} {
+		l := drainlevel([2]float64{5.5 + c.dist, 5.5})
+		l.Rules.Falloff = c.falloff
+		w := New(l, 1)
+		got := drained(w)[0]
+		if math.Abs(got-c.want) > 1e-9 {
+			t.Errorf("falloff %v at %v: drained %v, want %v", c.falloff, c.dist, got, c.want)
+		}
+		if sta := l.Stamina - Tickcost - c.want*l.Rules.Drain; math.Abs(w.Stamina-sta) > 1e-9 {
+			t.Errorf("falloff %v at %v: stamina %v, want %v", c.falloff, c.dist, w.Stamina, sta)
+		}
+	}
+}
+
+func TestSplit(t *testing.T) {
+	for _, c := range []struct {
+		name  string
+		split bool
+		at    [][2]float64
+		want  []float64
+	}{
+		{"one", true, [][2]float64{{5.5, 5.5}}, []float64{1}},
+		{"under the cap", true, [][2]float64{{7.5, 5.5}, {3.5, 5.5}}, []float64{0.5, 0.5}},
+		{"over the cap", true, [][2]float64{{5.5, 5.5}, {7.5, 5.5}}, []float64{2.0 / 3, 1.0 / 3}},
+		{"three", true, [][2]float64{{6.5, 5.5}, {4.5, 5.5}, {5.5, 6.5}}, []float64{1.0 / 3, 1.0 / 3, 1.0 / 3}},
+		{"not split", false, [][2]float64{{5.5, 5.5}, {7.5, 5.5}}, []float64{1, 0.5}},
+		{"out of reach", true, [][2]float64{{5.5, 5.5}, {9.5, 5.5}}, []float64{1, 0}},
+	} {
+		l := drainlevel(c.at...)
+		l.Rules.Split = c.split
+		w := New(l, 1)
+		got := drained(w)
+		sum := 0.0
+		for i := range c.want {
+			if math.Abs(got[i]-c.want[i]) > 1e-9 {
+				t.Errorf("%s: device %d drained %v, want %v", c.name, i, got[i], c.want[i])
+			}
+			sum += c.want[i]
+		}
+		if sta := l.Stamina - Tickcost - sum*l.Rules.Drain; math.Abs(w.Stamina-sta) > 1e-9 {
+			t.Errorf("%s: stamina %v, want %v", c.name, w.Stamina, sta)
+		}
+	}
+}
+
+// Score comes by the strength, the parts of a point add up over ticks.
+func TestDrainScore(t *testing.T) {
+	for _, c := range []struct {
+		name  string
+		at    [][2]float64
+		ticks int
+		want  int
+	}{
+		{"full", [][2]float64{{5.5, 5.5}}, 3, 3},
+		{"three quarters, one tick", [][2]float64{{6.5, 5.5}}, 1, 0},
+		{"three quarters, two ticks", [][2]float64{{6.5, 5.5}}, 2, 1},
+		{"three quarters, three ticks", [][2]float64{{6.5, 5.5}}, 3, 2},
+		{"three quarters, many ticks", [][2]float64{{6.5, 5.5}}, 100, 75},
+		{"split", [][2]float64{{5.5, 5.5}, {7.5, 5.5}}, 30, 30},
+	} {
+		w := New(drainlevel(c.at...), 1)
+		for i := 0; i < c.ticks; i++ {
+			w.Step(Input{})
+		}
+		if w.Newscore != c.want {
+			t.Errorf("%s: score %d, want %d", c.name, w.Newscore, c.want)
+		}
+	}
+}
+
+// chargelevel is drainlevel with Charge and one device on the player
+// that it drains at full strength.
+func chargelevel(dur float64) *Level {
+	l := drainlevel([2]float64{5.5, 5.5})
+	l.Flams[0].Dur = dur
+	l.Rules.Charge = true
+	l.Rules.Gain = 2
+	l.Rules.Overticks = 10
+	return l
+}
+
+func TestChargeGain(t *testing.T) {
+	l := chargelevel(100)
+	w := New(l, 1)
+	w.Stamina = 50
+	w.Step(Input{})
+	if want := 50 - Tickcost + l.Rules.Drain*l.Rules.Gain; math.Abs(w.Stamina-want) > 1e-9 {
+		t.Errorf("stamina %v, want %v", w.Stamina, want)
+	}
+	if w.Over != 0 {
+		t.Errorf("overcharged for %d ticks under the level's stamina", w.Over)
+	}
+}
+
+// Stamina over what the level starts with stops there and overcharges
+// the ball. Draining more refreshes the overcharge without starting it
+// again, after the last drain it lasts Overticks.
+func TestOvercharge(t *testing.T) {
+	const drains = 3
+	l := chargelevel(100)
+	w := New(l, 1)
+	overs := 0
+	for i := 0; i < drains+l.Rules.Overticks+5; i++ {
+		if i == drains {
+			w.Flams[0].X = 100 // out of reach from now on
+		}
+		if hasevent(w.Step(Input{}), Eover) {
+			overs++
+		}
+		if i < drains && w.Stamina != w.Origsta {
+			t.Errorf("tick %d: stamina %v, want %v", i, w.Stamina, w.Origsta)
+		}
+		want := l.Rules.Overticks
+		if i >= drains {
+			want -= i - drains + 1
+		}
+		if want < 0 {
+			want = 0
+		}
+		if int(w.Over) != want {
+			t.Errorf("tick %d: overcharge %d, want %d", i, w.Over, want)
+		}
+	}
+	if overs != 1 {
+		t.Errorf("overcharge started %d times, want 1", overs)
+	}
+}
+
+// Overcharged, walls and hazards cost nothing. The wall is still hit.
+func TestOverchargeCosts(t *testing.T) {
+	for _, over := range []uint{0, 20} {
+		l := testlevel(
+			"....",
+			".o#.",
+			"....",
+		)
+		l.Phys.Jitter = 0
+		l.Coll[1*l.W+1] = Cdamage
+		w := New(l, 1)
+		w.Over = over
+		walls, hurts := 0, 0
+		for i := 0; i < 10; i++ {
+			evs := w.Step(Input{Right: true})
+			if hasevent(evs, Ewall) {
+				walls++
+			}
+			if hasevent(evs, Ehurt) {
+				hurts++
+			}
+		}
+		if walls == 0 {
+			t.Fatalf("overcharge %d: the ball didn't hit the wall", over)
+		}
+		want := l.Stamina - 10*Tickcost
+		if over == 0 {
+			want -= float64(walls)*Wallcost + float64(hurts)*Hazardcost
+			if hurts == 0 {
+				t.Errorf("the ball wasn't hurt on the hazard")
+			}
+		} else if hurts != 0 {
+			t.Errorf("overcharged, the ball was hurt %d times", hurts)
+		}
+		if math.Abs(w.Stamina-want) > 1e-9 {
+			t.Errorf("overcharge %d: stamina %v, want %v", over, w.Stamina, want)
+		}
+	}
+}
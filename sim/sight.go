@@ -0,0 +1,82 @@
+package sim
+
+import "math"
+
+// Sees reports whether no wall is on the line from x0, y0 to x1, y1. It
+// walks every tile the line crosses; the tile of the end is not looked
+// at, so a device on a wall can be seen. A line right through a corner
+// is blocked if any tile at that corner is a wall, whichever way it goes.
+func (l *Level) Sees(x0, y0, x1, y1 float64) bool {
+	x, y := int(math.Floor(x0)), int(math.Floor(y0))
+	ex, ey := int(math.Floor(x1)), int(math.Floor(y1))
+	dx, dy := x1-x0, y1-y0
+
+	// tx, ty are how far along the line the next tile edge on each axis
+	// is, from 0 to 1, and sx, sy how far it is between edges.
+	stepx, tx, sx := 1, math.Inf(1), math.Inf(1)
+	if dx != 0 {
+		sx = math.Abs(1 / dx)
+		if dx > 0 {
+			tx = (float64(x+1) - x0) / dx
+		} else {
+			stepx = -1
+			tx = (x0 - float64(x)) / -dx
+		}
+	}
+	stepy, ty, sy := 1, math.Inf(1), math.Inf(1)
+	if dy != 0 {
+		sy = math.Abs(1 / dy)
+		if dy > 0 {
+			ty = (float64(y+1) - y0) / dy
+		} else {
+			stepy = -1
+			ty = (y0 - float64(y)) / -dy
+		}
+	}
+
+	for n := abs(ex-x) + abs(ey-y); n > 0; {
+		if l.Wall(x, y) {
+			return false
+		}
+		switch {
+		case math.Abs(tx-ty) < 1e-9:
+			if l.Wall(x+stepx, y) || l.Wall(x, y+stepy) {
+				return false
+			}
+			x += stepx
+			y += stepy
+			tx += sx
+			ty += sy
+			n -= 2
+		case tx < ty:
+			x += stepx
+			tx += sx
+			n--
+		default:
+			y += stepy
+			ty += sy
+			n--
+		}
+	}
+	return true
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
+
+// Drains reports whether the player at x, y drains the flammable: it is
+// in its reach, and if the rules say so, in sight of it.
+func (r *Rules) Drains(l *Level, f *Flammable, x, y float64) bool {
+	if !f.Reaches(x, y) {
+		return false
+	}
+	if !r.Sight {
+		return true
+	}
+	cx, cy := f.Centre()
+	return l.Sees(x, y, cx, cy)
+}
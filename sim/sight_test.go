@@ -0,0 +1,89 @@
+package sim
+
+import "testing"
+
+func TestSees(t *testing.T) {
+	for _, c := range []struct {
+		name           string
+		rows           []string
+		x0, y0, x1, y1 float64
+		want           bool
+	}{
+		{"open", []string{"...", "...", "..."}, 0.5, 0.5, 2.5, 2.2, true},
+		{"through a wall", []string{".#.", ".#.", "..."}, 0.5, 0.5, 2.5, 0.5, false},
+		{"past a wall", []string{".#.", ".#.", "..."}, 0.5, 2.5, 2.5, 2.5, true},
+		{"corner of one wall", []string{"...", "#..", "..."}, 0.5, 0.5, 1.5, 1.5, false},
+		{"corner of the other wall", []string{".#.", "...", "..."}, 0.5, 0.5, 1.5, 1.5, false},
+		{"between two walls", []string{".#.", "#..", "..."}, 0.5, 0.5, 1.5, 1.5, false},
+		{"diagonal past a corner", []string{"...", "#..", "..."}, 0.5, 0.4, 1.5, 1.2, true},
+		{"hidden around a corner", []string{
+			"....",
+			"###.",
+			"###.",
+			"...."}, 3.5, 0.5, 0.5, 3.5, false},
+		{"seen around a corner", []string{
+			"....",
+			"###.",
+			"###.",
+			"...."}, 3.5, 2.95, 0.5, 3.5, true},
+	} {
+		l := testlevel(c.rows...)
+		if got := l.Sees(c.x0, c.y0, c.x1, c.y1); got != c.want {
+			t.Errorf("%s: sees %v, want %v", c.name, got, c.want)
+		}
+		if got := l.Sees(c.x1, c.y1, c.x0, c.y0); got != c.want {
+			t.Errorf("%s reversed: sees %v, want %v", c.name, got, c.want)
+		}
+	}
+}
+
+// A device on a wall can be seen, the tile at the end is not looked at.
+func TestSeesOnWall(t *testing.T) {
+	l := testlevel("..#", "...", "...")
+	if !l.Sees(0.5, 0.5, 2.5, 0.5) {
+		t.Error("doesn't see the wall at the end")
+	}
+}
+
+// sightlevel has the player on one side of a wall and a device in reach
+// on the other, and one in reach in sight.
+func sightlevel(sight bool) *Level {
+	l := testlevel(
+		".....",
+		"..#..",
+		".o#..",
+		".....",
+		".....",
+	)
+	l.Phys.Jitter = 0
+	l.Rules.Sight = sight
+	l.Rules.Split = false
+	l.Flams = []Flammable{
+		{Typ: Freg, W: 1, H: 1, X: 3, Y: 2, Dur: 1, Rad: 3},   // behind the wall
+		{Typ: Freg, W: 1, H: 1, X: 0, Y: 0, Dur: 1, Rad: 3},   // in sight
+		{Typ: Freg, W: 1, H: 1, X: 3, Y: 4.2, Dur: 1, Rad: 3}, // around the end of the wall
+	}
+	return l
+}
+
+func TestDrainSight(t *testing.T) {
+	for _, c := range []struct {
+		sight bool
+		want  []bool
+	}{
+		{false, []bool{true, true, true}},
+		{true, []bool{false, true, true}},
+	} {
+		l := sightlevel(c.sight)
+		w := New(l, 1)
+		w.Step(Input{})
+		for i, f := range w.Flams {
+			if got := f.Dur < 1; got != c.want[i] {
+				t.Errorf("sight %v: device %d drained %v, want %v", c.sight, i, got, c.want[i])
+			}
+			if got := l.Rules.Drains(l, &l.Flams[i], l.Plx, l.Ply); got != c.want[i] {
+				t.Errorf("sight %v: Drains device %d is %v, want %v", c.sight, i, got, c.want[i])
+			}
+		}
+	}
+}
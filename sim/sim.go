@@ -0,0 +1,307 @@
+// Package sim is the gameplay core: player movement, stamina, walls and
+// draining of flammables. It doesn't touch ebiten, so it runs without a
+// display, one tick per Step.
+package sim
+
+import (
+	"math"
+	"math/rand"
+)
+
+const (
+	Tilesize  = 16
+	Tileprice = 0.25
+	Speed     = 0.05
+	Jitter    = 0.06
+
+	Tickcost  = 0.01 // stamina spent every tick
+	Draincost = 0.05 // stamina and durability per tick of draining
+	Wallcost  = 0.1  // stamina lost on hitting a wall
+
+	Hazardcost = 0.05 // stamina per tick on a damaging tile
+	Slowfactor = 0.5  // speed on a slow tile
+
+	Bounce     = 0.8  // part of the speed kept bouncing off a wall
+	Knockspd   = 0.06 // least speed a wall throws the ball back with
+	Knockdrag  = 0.9  // speed kept every tick of knockback
+	Knockticks = 12   // ticks without control after a bounce
+)
+
+// What happens when a flammable is destroyed.
+const (
+	Ftarg = iota // the level is cleared
+	Ftoas
+	Freg  // it explodes
+	Fnone // nothing, it is just gone
+)
+
+// Event is something that happened during a tick that the renderer or
+// the audio may want to react to.
+type Event int
+
+const (
+	Eexpl  Event = iota // flammable destroyed
+	Ewall               // player bounced off a wall
+	Ehurt               // player is on a damaging tile
+	Edead               // stamina is out
+	Eclear              // target reached
+	Eover               // overcharge started
+)
+
+type Flammable struct {
+	Typ        uint
+	Sprite     string
+	Deadsprite string
+	W          uint
+	H          uint
+	Rot        int
+
+	Dur   float64
+	Score int     // per tick of draining
+	Rad   float64 // drained this far from the centre, see Radius
+	X     float64
+	Y     float64
+	Dead  bool
+	Deadt int // ticks since it is dead
+	Chain int // devices in the chain of blasts that destroyed it
+}
+
+// Centre is the middle of the flammable in tiles.
+func (f *Flammable) Centre() (x, y float64) {
+	return f.X + float64(f.W)/2, f.Y + float64(f.H)/2
+}
+
+// Radius is how far from the centre the flammable is drained. Without
+// a radius of its own it is by its size.
+func (f *Flammable) Radius() float64 {
+	if f.Rad > 0 {
+		return f.Rad
+	}
+	return math.Sqrt(float64(f.W*f.H)/math.Pi) * 1.5
+}
+
+// Reaches reports whether the player at x, y drains the flammable.
+func (f *Flammable) Reaches(x, y float64) bool {
+	cx, cy := f.Centre()
+	dx, dy := cx-x, cy-y
+	return math.Sqrt(dx*dx+dy*dy) <= f.Radius()
+}
+
+// Input is the state of the controls during one tick.
+type Input struct {
+	Up    bool
+	Left  bool
+	Down  bool
+	Right bool
+
+	// Sx, Sy is an analog stick, used instead of the keys if not zero.
+	// Full speed is 127 along either axis or any length over it.
+	Sx int8
+	Sy int8
+}
+
+// World is a level being played.
+type World struct {
+	Lvl   *Level
+	Flams []Flammable
+
+	Plx      float64
+	Ply      float64
+	Vx       float64 // velocity in tiles per tick
+	Vy       float64
+	Knock    uint // ticks of knockback left
+	Over     uint // ticks of overcharge left
+	Phys     Physics
+	Rules    Rules
+	Stamina  float64
+	Origsta  float64
+	Newscore int
+	Dead     bool
+	Clear    bool
+	Tick     uint
+	Seed     int64
+
+	rng   *rand.Rand
+	score float64 // of draining, not yet in Newscore
+}
+
+// New starts a run of the level. All randomness of the run comes from
+// the seed, so the same seed and inputs give the same run.
+func New(l *Level, seed int64) *World {
+	w := &World{
+		Lvl:     l,
+		Flams:   make([]Flammable, len(l.Flams)),
+		Plx:     l.Plx,
+		Ply:     l.Ply,
+		Stamina: l.Stamina,
+		Origsta: l.Stamina,
+		Phys:    l.Phys,
+		Rules:   l.Rules,
+		Seed:    seed,
+		rng:     rand.New(rand.NewSource(seed)),
+	}
+	copy(w.Flams, l.Flams)
+	return w
+}
+
+// Step advances the world by one tick. It does nothing once the player
+// is dead or the level is cleared.
+func (w *World) Step(in Input) []Event {
+	if w.Dead || w.Clear {
+		return nil
+	}
+	defer func() { w.Tick++ }()
+	var evs []Event
+
+	w.Stamina -= Tickcost
+	if w.Stamina < 0 {
+		w.Dead = true
+		evs = append(evs, Edead)
+	}
+
+	evs = w.suck(evs)
+	evs = w.blast(evs)
+	evs = w.overcharge(evs)
+
+	if w.Knock > 0 {
+		w.Knock--
+		w.Vx *= Knockdrag
+		w.Vy *= Knockdrag
+	} else if w.Phys.Mode == Mmomentum {
+		w.Vx, w.Vy = w.accel(in)
+	} else {
+		w.Vx, w.Vy = w.steer(in, Speed*w.boost())
+	}
+
+	dx := w.Vx + (w.rng.Float64()-0.5)*2*w.Phys.Jitter
+	dy := w.Vy + (w.rng.Float64()-0.5)*2*w.Phys.Jitter
+
+	if nx, ny := w.move(dx, dy); nx != 0 || ny != 0 {
+		w.Vx = bounce(w.Vx, nx)
+		w.Vy = bounce(w.Vy, ny)
+		w.Knock = Knockticks
+		if w.Over == 0 {
+			w.Stamina -= Wallcost
+		}
+		evs = append(evs, Ewall)
+	}
+	if w.on() == Cdamage && w.Over == 0 {
+		w.Stamina -= Hazardcost
+		evs = append(evs, Ehurt)
+	}
+	return evs
+}
+
+// suck drains the flammables in reach, each by its strength. With
+// Split the strengths are shared out so that the player never drains
+// more than Drain in all.
+func (w *World) suck(evs []Event) []Event {
+	r := &w.Rules
+	str := make([]float64, len(w.Flams))
+	sum := 0.0
+	for i := range w.Flams {
+		e := &w.Flams[i]
+		if e.Dead {
+			e.Deadt++
+			continue
+		}
+		if r.Drains(w.Lvl, e, w.Plx, w.Ply) {
+			str[i] = r.strength(e, w.Plx, w.Ply)
+			sum += str[i]
+		}
+	}
+	if r.Split && sum > 1 {
+		for i := range str {
+			str[i] /= sum
+		}
+	}
+
+	for i, s := range str {
+		if s == 0 {
+			continue
+		}
+		e := &w.Flams[i]
+		dur := e.Dur
+		if r.Charge {
+			w.Stamina += r.Drain * s * r.Gain
+		} else {
+			w.Stamina -= r.Drain * s
+		}
+		e.Dur -= r.Drain * s
+		w.score += float64(e.Score) * s
+		if dur > 0 {
+			continue
+		}
+		e.Dead = true
+		switch e.Typ {
+		case Freg, Ftoas:
+			evs = append(evs, Eexpl)
+		case Ftarg:
+			evs = append(evs, Eexpl)
+			w.Newscore += int(w.Stamina * 10)
+			w.Clear = true
+			evs = append(evs, Eclear)
+		}
+	}
+	n := math.Floor(w.score)
+	w.Newscore += int(n)
+	w.score -= n
+	return evs
+}
+
+// steer is the vector of length speed the controls point at, or
+// shorter if the stick is not all the way.
+func (w *World) steer(in Input, speed float64) (dx, dy float64) {
+	switch {
+	case in.Sx != 0 || in.Sy != 0:
+		sx, sy := float64(in.Sx)/127, float64(in.Sy)/127
+		if l := math.Sqrt(sx*sx + sy*sy); l > 1 {
+			sx, sy = sx/l, sy/l
+		}
+		dx, dy = sx*speed, sy*speed
+	case in.Up && in.Left:
+		dy -= speed / math.Sqrt2
+		dx -= speed / math.Sqrt2
+	case in.Left && in.Down:
+		dx -= speed / math.Sqrt2
+		dy += speed / math.Sqrt2
+	case in.Down && in.Right:
+		dy += speed / math.Sqrt2
+		dx += speed / math.Sqrt2
+	case in.Right && in.Up:
+		dx += speed / math.Sqrt2
+		dy -= speed / math.Sqrt2
+	case in.Up:
+		dy -= speed
+	case in.Left:
+		dx -= speed
+	case in.Down:
+		dy += speed
+	case in.Right:
+		dx += speed
+	}
+	if w.on() == Cslow {
+		dx *= Slowfactor
+		dy *= Slowfactor
+	}
+	return dx, dy
+}
+
+// bounce reflects the velocity v off a wall with normal n along the
+// same axis, or keeps it if n is 0. The ball always leaves the wall with
+// at least Knockspd.
+func bounce(v, n float64) float64 {
+	if n == 0 {
+		return v
+	}
+	v = -v * Bounce
+	if v*n < Knockspd {
+		v = n * Knockspd
+	}
+	return v
+}
+
+// on is the collision of the tile under the centre of the ball.
+func (w *World) on() Coll {
+	return w.Lvl.Kind(int(math.Floor(w.Plx)), int(math.Floor(w.Ply)))
+}
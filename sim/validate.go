@@ -0,0 +1,118 @@
+package sim
+
+import (
+	"fmt"
+
+	"github.com/solarlune/ldtkgo"
+)
+
+// Problem is a mistake in a level that would only show up in play.
+type Problem struct {
+	Level  string
+	Entity string
+	X      int // pixel position of the entity
+	Y      int
+	Msg    string
+}
+
+func (p Problem) String() string {
+	if p.Entity == "" {
+		return fmt.Sprintf("%s: %s", p.Level, p.Msg)
+	}
+	return fmt.Sprintf("%s: %s at (%d, %d): %s", p.Level, p.Entity, p.X, p.Y, p.Msg)
+}
+
+// Validate checks the level for everything Load and the game expect.
+func Validate(proj *Project, lv *ldtkgo.Level) []Problem {
+	var ps []Problem
+	lvlerr := func(f string, args ...interface{}) {
+		ps = append(ps, Problem{Level: lv.Identifier, Msg: fmt.Sprintf(f, args...)})
+	}
+	enterr := func(e *ldtkgo.Entity, f string, args ...interface{}) {
+		ps = append(ps, Problem{
+			Level:  lv.Identifier,
+			Entity: e.Identifier,
+			X:      e.Position[0],
+			Y:      e.Position[1],
+			Msg:    fmt.Sprintf(f, args...),
+		})
+	}
+	number := func(e *ldtkgo.Entity, name string) {
+		p := e.PropertyByIdentifier(name)
+		if p == nil || p.IsNull() {
+			enterr(e, "no %s field", name)
+			return
+		}
+		if _, ok := p.Value.(float64); !ok {
+			enterr(e, "%s is not a number", name)
+		}
+	}
+
+	if lv.Width%Tilesize != 0 || lv.Height%Tilesize != 0 {
+		lvlerr("size %dx%d is not a multiple of %d", lv.Width, lv.Height, Tilesize)
+	}
+	if lv.LayerByIdentifier("AutoWalls") == nil {
+		lvlerr("no AutoWalls layer")
+	}
+	if _, err := loadphysics(lv); err != nil {
+		lvlerr("%v", err)
+	}
+	if _, err := loadrules(lv); err != nil {
+		lvlerr("%v", err)
+	}
+	ent := lv.LayerByIdentifier("Entities")
+	if ent == nil {
+		lvlerr("no Entities layer")
+		return ps
+	}
+
+	l := &Level{W: lv.Width / Tilesize, H: lv.Height / Tilesize}
+	l.loadcoll(proj, lv)
+
+	players, targets := 0, 0
+	for _, e := range ent.Entities {
+		if e.Identifier == "Player" {
+			players++
+			number(e, "Stamina")
+			if p := e.PropertyByIdentifier("Stamina"); p != nil && !p.IsNull() {
+				if v, ok := p.Value.(float64); ok && v <= 0 {
+					enterr(e, "Stamina is %v", v)
+				}
+			}
+			x := float64(e.Position[0]) / Tilesize
+			y := float64(e.Position[1]) / Tilesize
+			switch {
+			case x <= 0 || y <= 0 || x >= float64(l.W) || y >= float64(l.H):
+				enterr(e, "spawns outside of the level")
+			case l.Hits(x, y):
+				enterr(e, "spawns inside a wall")
+			}
+			continue
+		}
+		fl, msgs := flamof(e)
+		for _, m := range msgs {
+			enterr(e, "%s", m)
+		}
+		if fl.Typ == Ftarg {
+			targets++
+		}
+
+		if e.Position[0]%Tilesize != 0 || e.Position[1]%Tilesize != 0 {
+			enterr(e, "position is not a multiple of %d", Tilesize)
+		}
+		if e.Width%Tilesize != 0 || e.Height%Tilesize != 0 || e.Width == 0 || e.Height == 0 {
+			enterr(e, "size %dx%d is not a multiple of %d", e.Width, e.Height, Tilesize)
+		}
+	}
+
+	switch {
+	case players == 0:
+		lvlerr("no Player")
+	case players > 1:
+		lvlerr("%d Players, only the first one is used", players)
+	}
+	if targets == 0 {
+		lvlerr("no Target, the level can't be cleared")
+	}
+	return ps
+}